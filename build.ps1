@@ -9,7 +9,7 @@ if (Test-Path "ms-videos.exe") {
 
 # Build
 Write-Host "Compiling..." -ForegroundColor Yellow
-go build -ldflags "-s -w" -o ms-videos.exe cmd/ms-videos/main.go
+go build -ldflags "-s -w" -o ms-videos.exe ./cmd/ms-videos
 
 if ($LASTEXITCODE -eq 0) {
     Write-Host "Build successful! Executable: ms-videos.exe" -ForegroundColor Green
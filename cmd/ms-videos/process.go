@@ -0,0 +1,241 @@
+package main
+
+// Importações necessárias para o modo de linha de comando
+import (
+	"context"                      // Para o cancelamento por sinal
+	"errors"                       // Para identificar o pedido de ajuda
+	"flag"                         // Para os argumentos do subcomando
+	"fmt"                          // Para o resumo impresso
+	"io"                           // Para a saída do resumo
+	"ms-videos/internal/logging"   // Pacote interno de configuração do logging
+	"ms-videos/internal/processor" // Pacote interno para processamento de vídeos
+	"ms-videos/internal/queue"     // Pacote interno com a mensagem e os eventos
+	"ms-videos/internal/storage"   // Pacote interno para armazenamento de arquivos
+	"net/url"                      // Para converter caminhos locais em URLs file://
+	"os"                           // Para interação com sistema operacional
+	"os/signal"                    // Para captura de sinais do sistema
+	"path/filepath"                // Para manipulação de caminhos de arquivos
+	"regexp"                       // Para derivar o ID do nome do arquivo
+	"strings"                      // Para manipulação de strings
+	"sync"                         // Para proteger o evento capturado
+	"syscall"                      // Para constantes de sinais do sistema
+	"text/tabwriter"               // Para alinhar a tabela de variantes
+	"time"                         // Para o atraso entre tentativas de upload
+)
+
+// Códigos de saída do subcomando process
+const (
+	exitOK    = 0 // Vídeo processado
+	exitFail  = 1 // O processamento falhou
+	exitUsage = 2 // Argumentos inválidos
+)
+
+// Caracteres trocados por "_" ao derivar o ID e o nome do arquivo da entrada
+var (
+	invalidIDChars   = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// runProcess executa o subcomando "process": transcodifica um arquivo local ou
+// URL com o mesmo pipeline do serviço, sem RabbitMQ nem MinIO, gravando o HLS
+// em um diretório local e imprimindo um resumo das variantes
+// Retorna o código de saída do programa
+func runProcess(args []string) int {
+	fs := flag.NewFlagSet("process", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ms-videos process --input <file|url> --out <dir> [flags]")
+		fs.PrintDefaults()
+	}
+	input := fs.String("input", "", "source video: local file path or http(s)/file URL (required)")
+	out := fs.String("out", "", "output directory; the HLS files are written to <out>/<id>/ (required)")
+	profile := fs.String("profile", "", "encoding profile (default: the profile set's default)")
+	id := fs.String("id", "", "video ID used as the output prefix (default: derived from the input name)")
+	ladder := fs.String("ladder", getEnv("LADDER_CONFIG", ""), "encoding profiles JSON file (default: built-in profiles)")
+	mode := fs.String("mode", getEnv("ENCODE_MODE", processor.EncodeSinglePass), "encode mode: single or per_rendition")
+	threads := fs.Int("threads", getEnvInt("ENCODE_THREADS", 0), "total ffmpeg threads (0 = number of CPUs)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	// O resumo vai para stdout; os logs vão para stderr, em texto por padrão
+	if err := logging.Setup(os.Stderr, getEnv("LOG_LEVEL", "info"), getEnv("LOG_FORMAT", logging.FormatText)); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid logging configuration:", err)
+		return exitUsage
+	}
+
+	if *input == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "--input and --out are required")
+		fs.Usage()
+		return exitUsage
+	}
+	if *mode != processor.EncodeSinglePass && *mode != processor.EncodePerRendition {
+		fmt.Fprintf(os.Stderr, "invalid --mode %q: expected %s or %s\n", *mode, processor.EncodeSinglePass, processor.EncodePerRendition)
+		return exitUsage
+	}
+
+	msg, err := processMessage(*input, *id, *profile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	profiles, err := processor.LoadProfiles(*ladder)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if _, err := profiles.Get(msg.Profile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	store, err := storage.NewLocalBackend(*out, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFail
+	}
+
+	// A entrada vem de quem executa o comando, então fontes na rede local são aceitas
+	download := downloadConfig()
+	download.Policy.AllowPrivateIPs = true
+
+	// O evento completed traz as variantes produzidas para o resumo
+	var result completedEvent
+	videoProcessor := processor.NewVideoProcessor(store, processor.Config{
+		Profiles:          profiles,
+		EncodeMode:        *mode,
+		Threads:           *threads,
+		Download:          download,
+		UploadConcurrency: getEnvInt("UPLOAD_CONCURRENCY", 8),
+		UploadRetries:     getEnvInt("UPLOAD_RETRIES", 3),
+		UploadRetryDelay:  getEnvDuration("UPLOAD_RETRY_DELAY", time.Second),
+		Events:            &result,
+		AllowFileURLs:     true, // A entrada vem de quem executa o comando
+	})
+
+	// Ctrl+C interrompe o ffmpeg e limpa os arquivos temporários
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := videoProcessor.ProcessVideo(ctx, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "processing failed (%s): %v\n", queue.ErrorClass(err), err)
+		return exitFail
+	}
+
+	ev, ok := result.get()
+	if !ok {
+		fmt.Fprintln(os.Stderr, "processing finished without a completed event")
+		return exitFail
+	}
+	printSummary(os.Stdout, store, msg, ev)
+	return exitOK
+}
+
+// processMessage monta a mensagem do job a partir dos argumentos
+// Caminhos locais viram URLs file://; o ID padrão é o nome do arquivo sem extensão
+func processMessage(input, id, profile string) (queue.VideoMessage, error) {
+	sourceURL := input
+	name := filepath.Base(input)
+	if u, err := url.Parse(input); err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "file") {
+		name = filepath.Base(u.Path)
+	} else {
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return queue.VideoMessage{}, fmt.Errorf("invalid --input: %w", err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return queue.VideoMessage{}, fmt.Errorf("invalid --input: %w", err)
+		}
+		path := filepath.ToSlash(abs)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path // C:/videos/a.mp4 no Windows
+		}
+		sourceURL = (&url.URL{Scheme: "file", Path: path}).String()
+	}
+	if name == "." || name == "/" || name == "" {
+		name = "source"
+	}
+
+	if id == "" {
+		id = strings.Trim(invalidIDChars.ReplaceAllString(strings.TrimSuffix(name, filepath.Ext(name)), "_"), "_")
+		if id == "" {
+			id = "video"
+		}
+		id = id[:min(len(id), queue.MaxIDLength)]
+	} else if !queue.ValidID.MatchString(id) {
+		return queue.VideoMessage{}, fmt.Errorf("invalid --id %q: use 1-%d letters, digits, '-' and '_'", id, queue.MaxIDLength)
+	}
+
+	msg := queue.VideoMessage{
+		ID:       id,
+		URL:      sourceURL,
+		Filename: invalidNameChars.ReplaceAllString(name, "_"),
+		Profile:  profile,
+	}
+	if err := msg.Validate(); err != nil {
+		return queue.VideoMessage{}, err
+	}
+	return msg, nil
+}
+
+// completedEvent guarda o evento completed publicado pelo processador
+type completedEvent struct {
+	mu sync.Mutex
+	ev *queue.JobEvent
+}
+
+// PublishEvent implementa processor.EventPublisher
+func (c *completedEvent) PublishEvent(ctx context.Context, ev queue.JobEvent) error {
+	if ev.Type != queue.EventCompleted {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ev = &ev
+	return nil
+}
+
+// get retorna o evento completed, se houver
+func (c *completedEvent) get() (queue.JobEvent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ev == nil {
+		return queue.JobEvent{}, false
+	}
+	return *c.ev, true
+}
+
+// printSummary imprime a playlist mestre, o hash da fonte e uma linha por variante produzida,
+// com resolução, bitrates, codecs, número de segmentos e tamanho em disco
+func printSummary(w io.Writer, store *storage.LocalBackend, msg queue.VideoMessage, ev queue.JobEvent) {
+	ctx := context.Background()
+	master, _ := store.PresignGet(ctx, msg.ID+"/master.m3u8", 0)
+	fmt.Fprintf(w, "Processed %s in %.1fs (source duration %.1fs)\n", msg.ID, ev.ElapsedSeconds, ev.DurationSeconds)
+	fmt.Fprintf(w, "Master playlist: %s\n", strings.TrimPrefix(master, "file://"))
+	if ev.Source != nil {
+		fmt.Fprintf(w, "Source: %.1f MiB, sha256 %s\n", float64(ev.Source.Size)/(1<<20), ev.Source.SHA256)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RENDITION\tRESOLUTION\tFPS\tAVG KBPS\tPEAK KBPS\tCODECS\tSEGMENTS\tSIZE\tPLAYLIST")
+	for _, r := range ev.Renditions {
+		// A listagem do diretório da variante dá o número de segmentos e o tamanho
+		var segments int
+		var size int64
+		objects, _ := store.List(ctx, strings.TrimSuffix(r.Playlist, "playlist.m3u8"))
+		for _, obj := range objects {
+			size += obj.Size
+			if strings.HasSuffix(obj.Key, ".ts") {
+				segments++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%dx%d\t%.2f\t%d\t%d\t%s\t%d\t%.1f MiB\t%s\n",
+			r.Name, r.Width, r.Height, r.FrameRate, r.AverageBandwidth/1000, r.PeakBandwidth/1000,
+			r.Codecs, segments, float64(size)/(1<<20), r.Playlist)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,29 @@
+package api
+
+// Importações necessárias para os endpoints de saúde
+import (
+	"ms-videos/internal/health" // Para as verificações de prontidão
+	"net/http"                  // Para o servidor HTTP
+	"time"                      // Para o timeout das verificações
+)
+
+// readinessTimeout limita cada verificação de prontidão
+const readinessTimeout = 5 * time.Second
+
+// handleHealthz atende o liveness probe: responde enquanto o processo estiver de pé
+// Dependências externas ficam de fora para que uma queda do broker não reinicie o pod
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// handleReadyz atende o readiness probe: executa todas as verificações e
+// responde 503 se alguma falhar, com o resultado de cada uma
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(r.Context(), readinessTimeout, s.config.Readiness)
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
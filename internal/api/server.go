@@ -0,0 +1,340 @@
+// Package api expõe a API HTTP de controle do serviço
+// Permite submeter jobs, consultar o estado e o progresso, cancelar jobs e
+// responder aos probes de liveness e readiness e expor as métricas Prometheus
+package api
+
+// Importações necessárias para a API HTTP
+import (
+	"context"                      // Para os timeouts de publicação e desligamento
+	"crypto/rand"                  // Para gerar IDs de jobs
+	"encoding/hex"                 // Para codificar os IDs gerados
+	"encoding/json"                // Para serialização das requisições e respostas
+	"errors"                       // Para identificar os erros do store
+	"fmt"                          // Para formatação de strings
+	"log/slog"                     // Para logging estruturado
+	"math"                         // Para arredondar o progresso
+	"ms-videos/internal/health"    // Para as verificações de prontidão
+	"ms-videos/internal/jobs"      // Para o estado dos jobs
+	"ms-videos/internal/processor" // Para perfis, progresso e cancelamento
+	"ms-videos/internal/queue"     // Para as mensagens publicadas
+	"net/http"                     // Para o servidor HTTP
+	"net/url"                      // Para validar a URL da fonte
+	"path"                         // Para derivar o nome do arquivo da URL
+	"strconv"                      // Para os parâmetros de listagem
+	"strings"                      // Para manipulação de strings
+	"time"                         // Para os timeouts do servidor
+
+	"github.com/prometheus/client_golang/prometheus/promhttp" // Para expor as métricas
+)
+
+// Limites da API
+const (
+	maxBodyBytes     = 1 << 20          // Tamanho máximo do corpo de uma submissão
+	defaultListLimit = 100              // Jobs retornados por padrão em GET /jobs
+	maxListLimit     = 1000             // Máximo de jobs em GET /jobs
+	publishTimeout   = 10 * time.Second // Tempo máximo para publicar um job na fila
+)
+
+// JobPublisher publica jobs na fila de vídeos
+// queue.RabbitMQPublisher e queue.MemoryBroker implementam esta interface
+type JobPublisher interface {
+	PublishJob(ctx context.Context, queueName string, msg queue.VideoMessage) error
+}
+
+// JobController dá acesso aos jobs em andamento nesta instância
+// processor.VideoProcessor implementa esta interface
+type JobController interface {
+	Cancel(id string) bool
+	Progress(id string) (map[string]processor.Progress, bool)
+}
+
+// Config reúne as dependências do servidor
+type Config struct {
+	Addr      string                // Endereço de escuta (ex: ":8080")
+	Queue     string                // Fila onde os jobs submetidos são publicados
+	Store     *jobs.Store           // Estado dos jobs
+	Publisher JobPublisher          // Publicação dos jobs submetidos
+	Processor JobController         // Progresso ao vivo e cancelamento
+	Profiles  *processor.ProfileSet // Perfis aceitos nas submissões
+	Readiness []health.Check        // Verificações executadas em /readyz
+}
+
+// Server é o servidor HTTP da API de controle
+type Server struct {
+	config     Config
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+// NewServer cria o servidor e registra as rotas
+func NewServer(config Config) *Server {
+	s := &Server{
+		config: config,
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/jobs", s.handleJobs)
+	s.mux.HandleFunc("/jobs/", s.handleJob)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.mux.Handle("/metrics", promhttp.Handler())
+
+	s.httpServer = &http.Server{
+		Addr:              config.Addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// ListenAndServe atende requisições até Shutdown ser chamado
+func (s *Server) ListenAndServe() error {
+	slog.Info("HTTP API listening", "addr", s.config.Addr)
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown para de aceitar conexões e espera as requisições em andamento
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleJobs atende /jobs: GET lista, POST submete
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listJobs(w, r)
+	case http.MethodPost:
+		s.submitJob(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleJob atende /jobs/{id}: GET consulta, DELETE cancela
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getJob(w, id)
+	case http.MethodDelete:
+		s.cancelJob(w, id)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// submitRequest é o corpo de POST /jobs
+type submitRequest struct {
+	ID       string            `json:"id"`       // Opcional: gerado quando vazio
+	URL      string            `json:"url"`      // URL da fonte (http, https ou s3)
+	Filename string            `json:"filename"` // Opcional: derivado da URL quando vazio
+	Profile  string            `json:"profile"`  // Opcional: perfil padrão quando vazio
+	Auth     *queue.SourceAuth `json:"auth"`     // Opcional: credenciais da fonte http ou https
+	Checksum string            `json:"checksum"` // Opcional: "sha256:<hex>" ou "md5:<hex>" esperado da fonte
+	Size     int64             `json:"size"`     // Opcional: tamanho esperado da fonte em bytes
+}
+
+// submitJob valida a submissão, registra o job e o publica na fila
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req submitRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	msg, err := s.validate(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := s.config.Store.Create(msg)
+	if errors.Is(err, jobs.ErrExists) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job %s already exists", msg.ID))
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to persist job", "job_id", msg.ID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to persist job")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), publishTimeout)
+	defer cancel()
+	if err := s.config.Publisher.PublishJob(ctx, s.config.Queue, msg); err != nil {
+		slog.Error("Failed to publish job", "job_id", msg.ID, "error", err)
+		if err := s.config.Store.Remove(msg.ID); err != nil {
+			slog.Warn("Failed to remove unpublished job", "job_id", msg.ID, "error", err)
+		}
+		writeError(w, http.StatusServiceUnavailable, "failed to enqueue job")
+		return
+	}
+
+	slog.Info("Submitted video via API", "job_id", msg.ID, "stage", "queued")
+	w.Header().Set("Location", "/jobs/"+msg.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// validate confere a submissão e monta a mensagem que vai para a fila
+func (s *Server) validate(req submitRequest) (queue.VideoMessage, error) {
+	msg := queue.VideoMessage{
+		ID:       req.ID,
+		URL:      strings.TrimSpace(req.URL),
+		Filename: req.Filename,
+		Profile:  req.Profile,
+		Auth:     req.Auth,
+		Checksum: req.Checksum,
+		Size:     req.Size,
+	}
+
+	if msg.URL == "" {
+		return msg, fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(msg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "s3") || u.Host == "" {
+		return msg, fmt.Errorf("url must be an absolute http, https or s3 URL")
+	}
+
+	if msg.ID == "" {
+		msg.ID = newID()
+	}
+	if msg.Filename == "" {
+		msg.Filename = path.Base(u.Path)
+		if strings.HasSuffix(u.Path, "/") || msg.Filename == "." || msg.Filename == "/" {
+			return msg, fmt.Errorf("url has no file name; set filename")
+		}
+	}
+
+	// As mesmas regras aplicadas pelo worker: uma submissão aceita aqui nunca é
+	// rejeitada ao chegar da fila
+	if err := msg.Validate(); err != nil {
+		return msg, err
+	}
+
+	if msg.Profile != "" {
+		if _, err := s.config.Profiles.Get(msg.Profile); err != nil {
+			return msg, err
+		}
+	}
+	return msg, nil
+}
+
+// listJobs atende GET /jobs?state=&profile=&limit=
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := jobs.Filter{
+		State:   query.Get("state"),
+		Profile: query.Get("profile"),
+		Limit:   defaultListLimit,
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = min(n, maxListLimit)
+	}
+
+	list := s.config.Store.List(filter)
+	for i := range list {
+		s.withLiveProgress(&list[i])
+	}
+	if list == nil {
+		list = []jobs.Job{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": list})
+}
+
+// getJob atende GET /jobs/{id}
+func (s *Server) getJob(w http.ResponseWriter, id string) {
+	job, err := s.config.Store.Get(id)
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	}
+	s.withLiveProgress(&job)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// cancelJob atende DELETE /jobs/{id}
+// Jobs na fila são descartados quando chegam ao worker desta instância; jobs em
+// andamento nesta instância são interrompidos imediatamente. O estado não é
+// compartilhado entre réplicas: jobs em andamento em outra instância não são cancelados
+func (s *Server) cancelJob(w http.ResponseWriter, id string) {
+	job, running, err := s.config.Store.Cancel(id, s.config.Processor.Cancel)
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeError(w, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	case errors.Is(err, jobs.ErrFinished):
+		writeError(w, http.StatusConflict, fmt.Sprintf("job %s is already %s", id, job.State))
+		return
+	case errors.Is(err, jobs.ErrNotLocal):
+		writeError(w, http.StatusConflict, fmt.Sprintf("job %s is %s on another instance and cannot be canceled here", id, job.State))
+		return
+	case err != nil:
+		slog.Warn("Failed to persist job cancellation", "job_id", id, "error", err)
+	}
+
+	if running {
+		slog.Info("Canceled running video via API", "job_id", id)
+	} else {
+		slog.Info("Canceled queued video via API", "job_id", id)
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// withLiveProgress troca o progresso registrado pelos eventos pelo progresso
+// mais recente do ffmpeg, quando o job está sendo codificado nesta instância
+func (s *Server) withLiveProgress(job *jobs.Job) {
+	live, ok := s.config.Processor.Progress(job.ID)
+	if !ok {
+		return
+	}
+	if job.Progress == nil {
+		job.Progress = make(map[string]jobs.RenditionProgress, len(live))
+	}
+	for name, p := range live {
+		job.Progress[name] = jobs.RenditionProgress{
+			Percent:        math.Round(p.Percent*10) / 10,
+			OutTimeSeconds: p.OutTime.Seconds(),
+			Speed:          p.Speed,
+			FPS:            p.FPS,
+		}
+	}
+}
+
+// newID gera um ID aleatório para jobs submetidos sem ID
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate job id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// writeJSON escreve v como resposta JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write HTTP response", "error", err)
+	}
+}
+
+// writeError escreve uma resposta de erro no formato {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package health
+
+// Importações necessárias para consultar o espaço livre em disco
+import (
+	"syscall" // Para statfs
+)
+
+// freeBytes retorna quantos bytes estão disponíveis para usuários comuns em dir
+func freeBytes(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package health
+
+// Importações necessárias para consultar o espaço livre em disco
+import (
+	"syscall" // Para chamar a API do Windows
+	"unsafe"  // Para passar ponteiros à API do Windows
+)
+
+// getDiskFreeSpaceEx é a função da kernel32 que informa o espaço livre de um volume
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeBytes retorna quantos bytes estão disponíveis para o usuário atual em dir
+func freeBytes(dir string) (uint64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var available, total, free uint64
+	ok, _, err := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if ok == 0 {
+		return 0, err
+	}
+	return available, nil
+}
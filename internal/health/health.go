@@ -0,0 +1,116 @@
+// Package health contém as verificações de saúde usadas pelos probes do Kubernetes
+// Cada verificação é independente e reporta seu próprio status
+package health
+
+// Importações necessárias para as verificações de saúde
+import (
+	"context" // Para o timeout das verificações
+	"fmt"     // Para formatação de strings
+	"os/exec" // Para executar ffmpeg/ffprobe
+	"sync"    // Para rodar as verificações em paralelo
+	"time"    // Para medir a duração das verificações
+)
+
+// Status de uma verificação ou do relatório completo
+const (
+	StatusOK   = "ok"   // Tudo certo
+	StatusFail = "fail" // Ao menos uma verificação falhou
+)
+
+// Check é uma verificação nomeada; Run retorna nil quando o recurso está saudável
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result é o resultado de uma verificação
+type Result struct {
+	Status     string  `json:"status"`          // StatusOK ou StatusFail
+	Error      string  `json:"error,omitempty"` // Motivo da falha
+	DurationMs float64 `json:"duration_ms"`     // Tempo gasto na verificação
+}
+
+// Report agrupa os resultados de todas as verificações
+type Report struct {
+	Status string            `json:"status"` // StatusOK somente se todas passaram
+	Checks map[string]Result `json:"checks"` // Resultado de cada verificação pelo nome
+}
+
+// Run executa as verificações em paralelo, cada uma limitada por timeout
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			started := time.Now()
+			err := check.Run(checkCtx)
+			result := Result{Status: StatusOK, DurationMs: float64(time.Since(started).Microseconds()) / 1000}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Binary verifica se o executável está no PATH e roda com "-version"
+func Binary(name string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				return fmt.Errorf("%s not found in PATH: %w", name, err)
+			}
+			if out, err := exec.CommandContext(ctx, path, "-version").CombinedOutput(); err != nil {
+				return fmt.Errorf("%s -version failed: %w: %s", name, err, truncate(string(out), 200))
+			}
+			return nil
+		},
+	}
+}
+
+// DiskSpace verifica se o diretório tem ao menos minFree bytes livres
+func DiskSpace(name, dir string, minFree uint64) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			free, err := freeBytes(dir)
+			if err != nil {
+				return fmt.Errorf("failed to get free space of %s: %w", dir, err)
+			}
+			if free < minFree {
+				return fmt.Errorf("only %d MiB free in %s, need at least %d MiB", free>>20, dir, minFree>>20)
+			}
+			return nil
+		},
+	}
+}
+
+// truncate limita s a n bytes, para não despejar saídas longas no relatório
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,361 @@
+// Package jobs mantém o estado dos jobs de vídeo conhecido por esta instância
+// O estado é atualizado pelos eventos do ciclo de vida e pode ser persistido
+// num arquivo local para sobreviver a reinicializações
+package jobs
+
+// Importações necessárias para o armazenamento dos jobs
+import (
+	"context"                  // Para implementar a interface de publicação de eventos
+	"encoding/json"            // Para persistência em arquivo
+	"errors"                   // Para os erros do store
+	"fmt"                      // Para formatação de strings
+	"ms-videos/internal/queue" // Para mensagens e eventos
+	"os"                       // Para leitura e escrita do arquivo
+	"path/filepath"            // Para manipulação de caminhos de arquivos
+	"sort"                     // Para ordenar as listagens
+	"sync"                     // Para acesso concorrente
+	"time"                     // Para os horários dos jobs
+)
+
+// Estados de um job
+const (
+	StateQueued      = "queued"      // Publicado na fila, ainda não recebido
+	StateReceived    = "received"    // Recebido por um worker
+	StateDownloading = "downloading" // Baixando a fonte
+	StateEncoding    = "encoding"    // Gerando as variantes
+	StateUploading   = "uploading"   // Enviando os arquivos HLS
+	StateCompleted   = "completed"   // Concluído com sucesso
+	StateFailed      = "failed"      // Falhou (pode ser re-tentado, veja ErrorClass)
+	StateCanceled    = "canceled"    // Cancelado a pedido
+)
+
+// Por quanto tempo os jobs continuam no store depois da última mudança
+const (
+	finishedRetention = 7 * 24 * time.Hour // Jobs finalizados
+	// staleRetention vale para jobs que nunca chegaram a um estado final nesta
+	// instância: ficaram na fila, foram processados por outra instância ou
+	// falharam com erro retryable e acabaram na dead-letter queue
+	staleRetention = 30 * 24 * time.Hour
+)
+
+// Erros retornados pelo store
+var (
+	ErrNotFound = errors.New("job not found")            // ID desconhecido
+	ErrExists   = errors.New("job already exists")       // ID já usado por outro job
+	ErrFinished = errors.New("job has already finished") // Job em estado final
+	// ErrNotLocal indica um job em andamento que não está sendo processado nesta
+	// instância: o estado e o cancelamento não são compartilhados entre réplicas
+	ErrNotLocal = errors.New("job is not running on this instance")
+)
+
+// Job é o estado conhecido de um vídeo
+type Job struct {
+	ID           string                       `json:"id"`                      // Identificador do vídeo
+	URL          string                       `json:"url,omitempty"`           // URL da fonte
+	Filename     string                       `json:"filename,omitempty"`      // Nome do arquivo da fonte
+	Profile      string                       `json:"profile,omitempty"`       // Perfil de encoding pedido
+	State        string                       `json:"state"`                   // Estado atual (StateQueued, ...)
+	Attempts     int                          `json:"attempts"`                // Quantas vezes o job foi recebido por um worker
+	Progress     map[string]RenditionProgress `json:"progress,omitempty"`      // Progresso de cada variante
+	ManifestKeys []string                     `json:"manifest_keys,omitempty"` // Chaves das playlists geradas
+	Source       *queue.SourceInfo            `json:"source,omitempty"`        // Hash e tamanho da fonte processada
+	Error        string                       `json:"error,omitempty"`         // Último erro
+	ErrorClass   string                       `json:"error_class,omitempty"`   // Classe do último erro
+	CreatedAt    time.Time                    `json:"created_at"`              // Momento em que o job foi conhecido
+	UpdatedAt    time.Time                    `json:"updated_at"`              // Momento da última mudança
+}
+
+// RenditionProgress é o progresso do encoding de uma variante
+type RenditionProgress struct {
+	Percent        float64 `json:"percent"`                    // De 0 a 100
+	OutTimeSeconds float64 `json:"out_time_seconds,omitempty"` // Quanto da fonte já foi codificado
+	Speed          float64 `json:"speed,omitempty"`            // Velocidade em relação ao tempo real
+	FPS            float64 `json:"fps,omitempty"`              // Quadros codificados por segundo
+}
+
+// Finished indica se o job está num estado final
+// Jobs com falha retryable ainda podem voltar a ser processados
+func (j *Job) Finished() bool {
+	switch j.State {
+	case StateCompleted, StateCanceled:
+		return true
+	case StateFailed:
+		return j.ErrorClass != queue.ErrorClassRetryable
+	default:
+		return false
+	}
+}
+
+// Filter restringe uma listagem de jobs
+type Filter struct {
+	State   string // Apenas jobs neste estado (vazio = todos)
+	Profile string // Apenas jobs deste perfil (vazio = todos)
+	Limit   int    // Máximo de jobs retornados (0 = sem limite)
+}
+
+// Store guarda os jobs em memória, opcionalmente persistidos num arquivo JSON
+type Store struct {
+	mu   sync.Mutex      // Protege jobs e a escrita do arquivo
+	path string          // Arquivo de persistência ("" = apenas em memória)
+	jobs map[string]*Job // Jobs indexados pelo ID
+}
+
+// NewStore cria um store e carrega os jobs salvos em path, se o arquivo existir
+// Com path vazio, o estado fica apenas em memória
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		jobs: make(map[string]*Job),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs store: %w", err)
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs store %s: %w", path, err)
+	}
+	for _, j := range jobs {
+		s.jobs[j.ID] = j
+	}
+	return s, nil
+}
+
+// Create registra um job recém-publicado na fila
+func (s *Store) Create(msg queue.VideoMessage) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[msg.ID]; ok {
+		return Job{}, ErrExists
+	}
+
+	now := time.Now().UTC()
+	j := &Job{
+		ID:        msg.ID,
+		URL:       msg.URL,
+		Filename:  msg.Filename,
+		Profile:   msg.Profile,
+		State:     StateQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.jobs[j.ID] = j
+	if err := s.saveLocked(); err != nil {
+		delete(s.jobs, j.ID) // A submissão falha e pode ser repetida com o mesmo ID
+		return Job{}, err
+	}
+	return j.clone(), nil
+}
+
+// Remove descarta um job, usado quando a publicação na fila falha
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return s.saveLocked()
+}
+
+// Get retorna uma cópia do job
+func (s *Store) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return j.clone(), nil
+}
+
+// List retorna os jobs que atendem ao filtro, do mais recente para o mais antigo
+func (s *Store) List(f Filter) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Job
+	for _, j := range s.jobs {
+		if f.State != "" && j.State != f.State {
+			continue
+		}
+		if f.Profile != "" && j.Profile != f.Profile {
+			continue
+		}
+		out = append(out, j.clone())
+	}
+
+	sort.Slice(out, func(a, b int) bool {
+		return out[a].CreatedAt.After(out[b].CreatedAt)
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out
+}
+
+// Cancel marca o job como cancelado
+// stop interrompe o job se ele estiver em andamento nesta instância
+// (VideoProcessor.Cancel) e indica se o encontrou. Um job esperando na fila é
+// marcado e descartado quando chegar ao worker desta instância; um job em
+// andamento que stop não encontrou está em outra réplica e não é marcado
+// (ErrNotLocal), já que ela continuaria a processá-lo
+// Retorna também se o job estava em andamento aqui
+func (s *Store) Cancel(id string, stop func(id string) bool) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false, ErrNotFound
+	}
+	if j.Finished() {
+		return j.clone(), false, ErrFinished
+	}
+
+	running := stop(id)
+	if !running && !j.waiting() {
+		return j.clone(), false, ErrNotLocal
+	}
+	j.State = StateCanceled
+	j.UpdatedAt = time.Now().UTC()
+	return j.clone(), running, s.saveLocked()
+}
+
+// waiting indica se o job está na fila, inclusive esperando uma nova tentativa
+func (j *Job) waiting() bool {
+	return j.State == StateQueued || (j.State == StateFailed && j.ErrorClass == queue.ErrorClassRetryable)
+}
+
+// IsCanceled indica se o job foi cancelado
+func (s *Store) IsCanceled(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	return ok && j.State == StateCanceled
+}
+
+// PublishEvent aplica um evento do ciclo de vida ao job correspondente
+// Implementa processor.EventPublisher; jobs desconhecidos (publicados direto
+// na fila) passam a ser acompanhados a partir do primeiro evento
+func (s *Store) PublishEvent(ctx context.Context, ev queue.JobEvent) error {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[ev.JobID]
+	if !ok {
+		j = &Job{ID: ev.JobID, CreatedAt: ev.Timestamp}
+		s.jobs[j.ID] = j
+	}
+	// Um job cancelado só muda se voltar a ser processado, o que não acontece
+	if j.State == StateCanceled && ev.Type != queue.EventCanceled {
+		return nil
+	}
+	previous := j.State
+
+	switch ev.Type {
+	case queue.EventReceived:
+		j.State = StateReceived
+		j.Attempts++
+		j.Progress = nil
+		j.Error, j.ErrorClass = "", ""
+	case queue.EventDownloading:
+		j.State = StateDownloading
+	case queue.EventEncoding:
+		j.State = StateEncoding
+		if j.Progress == nil {
+			j.Progress = make(map[string]RenditionProgress)
+		}
+		j.Progress[ev.Rendition] = RenditionProgress{
+			Percent:        ev.Percent,
+			OutTimeSeconds: ev.OutTimeSeconds,
+			Speed:          ev.Speed,
+			FPS:            ev.FPS,
+		}
+	case queue.EventUploading:
+		j.State = StateUploading
+	case queue.EventCompleted:
+		j.State = StateCompleted
+		j.ManifestKeys = ev.ManifestKeys
+		j.Source = ev.Source
+	case queue.EventFailed:
+		j.State = StateFailed
+		j.Error, j.ErrorClass = ev.Error, ev.ErrorClass
+	case queue.EventCanceled:
+		j.State = StateCanceled
+	default:
+		return nil
+	}
+	j.UpdatedAt = ev.Timestamp
+	// O progresso do encoding chega a cada linha do ffmpeg e fica só em memória;
+	// o arquivo é gravado apenas nas mudanças de estado
+	if ev.Type == queue.EventEncoding && previous == StateEncoding {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// saveLocked descarta jobs antigos e grava o store no arquivo
+// A escrita é atômica: um arquivo temporário substitui o anterior; exige s.mu travado
+func (s *Store) saveLocked() error {
+	now := time.Now()
+	for id, j := range s.jobs {
+		retention := staleRetention
+		if j.Finished() {
+			retention = finishedRetention
+		}
+		if j.UpdatedAt.Before(now.Add(-retention)) {
+			delete(s.jobs, id)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal jobs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create jobs store directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write jobs store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace jobs store: %w", err)
+	}
+	return nil
+}
+
+// clone copia o job, inclusive mapas e slices, para uso fora do lock
+func (j *Job) clone() Job {
+	c := *j
+	if j.Progress != nil {
+		c.Progress = make(map[string]RenditionProgress, len(j.Progress))
+		for name, p := range j.Progress {
+			c.Progress[name] = p
+		}
+	}
+	c.ManifestKeys = append([]string(nil), j.ManifestKeys...)
+	return c
+}
@@ -0,0 +1,167 @@
+// Package metrics define as métricas Prometheus do serviço
+// As métricas são registradas no registry padrão e expostas em /metrics
+package metrics
+
+// Importações necessárias para as métricas
+import (
+	"github.com/prometheus/client_golang/prometheus"          // Tipos de métricas
+	"github.com/prometheus/client_golang/prometheus/promauto" // Registro automático
+)
+
+// namespace prefixa todas as métricas do serviço
+const namespace = "ms_videos"
+
+// Etapas usadas no label "stage" de StageDuration
+const (
+	StageDownload = "download"
+	StageProbe    = "probe"
+	StageEncode   = "encode"
+	StageUpload   = "upload"
+)
+
+// RenditionAll é o valor do label "rendition" quando um único ffmpeg gera
+// todas as variantes (modo de passada única)
+const RenditionAll = "all"
+
+// Métricas dos jobs
+var (
+	// JobsReceived conta os jobs que começaram a ser processados
+	JobsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_received_total",
+		Help:      "Jobs that started processing.",
+	})
+	// JobsSucceeded conta os jobs concluídos com sucesso
+	JobsSucceeded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_succeeded_total",
+		Help:      "Jobs that completed successfully.",
+	})
+	// JobsFailed conta os jobs que falharam, pela classe do erro
+	JobsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_failed_total",
+		Help:      "Jobs that failed, by error class (retryable, permanent, canceled).",
+	}, []string{"error_class"})
+	// JobsInFlight é o número de jobs em processamento
+	JobsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_in_flight",
+		Help:      "Jobs currently being processed.",
+	})
+	// JobStarted guarda o início (Unix) de cada job em processamento; a série
+	// é removida quando o job termina
+	JobStarted = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_in_flight_started_seconds",
+		Help:      "Start time of the job currently being processed, as a Unix timestamp.",
+	}, []string{"job_id", "profile"})
+)
+
+// Métricas das etapas
+var (
+	// StageDuration mede a duração de cada etapa; o encode é medido por variante
+	StageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of each processing stage; encode is labeled by rendition.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 16), // 0.5 s a ~4.5 h
+	}, []string{"stage", "rendition"})
+	// EncodeSpeed é a razão entre a duração da fonte e o tempo de encoding
+	// (2 = duas vezes mais rápido que o tempo real)
+	EncodeSpeed = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "encode_speed_ratio",
+		Help:      "Source duration divided by encode wall time, by rendition.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 3, 5, 10, 20},
+	}, []string{"rendition"})
+	// DownloadedBytes conta os bytes baixados das fontes
+	DownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes downloaded from video sources.",
+	})
+	// DownloadThroughput mede a taxa de cada download de fonte concluído,
+	// incluindo as pausas entre tentativas
+	DownloadThroughput = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "download_throughput_bytes_per_second",
+		Help:      "Average throughput of each completed source download.",
+		Buckets:   prometheus.ExponentialBuckets(128<<10, 2, 14), // 128 KiB/s a 1 GiB/s
+	})
+	// DownloadRetries conta as novas tentativas de download após falhas transitórias
+	DownloadRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_retries_total",
+		Help:      "Source download attempts retried after a transient failure.",
+	})
+	// UploadedBytes conta os bytes enviados ao armazenamento
+	UploadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Bytes of HLS output uploaded to storage.",
+	})
+)
+
+// Métricas da fila
+var (
+	// Redeliveries conta as mensagens que o broker entregou de novo (por queda
+	// de conexão ou desligamento antes da confirmação)
+	Redeliveries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_redeliveries_total",
+		Help:      "Deliveries flagged as redelivered by the broker.",
+	})
+	// Retries conta as mensagens enviadas para uma fila de espera de retentativa
+	Retries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_retries_total",
+		Help:      "Messages scheduled for a delayed retry.",
+	})
+	// DeliveriesLost conta os jobs interrompidos porque a conexão com o broker
+	// caiu antes da confirmação (a mensagem é reentregue)
+	DeliveriesLost = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_deliveries_lost_total",
+		Help:      "Jobs canceled because the broker connection closed before they could be acknowledged.",
+	})
+	// DeadLettered conta as mensagens enviadas para a dead-letter queue
+	DeadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_dead_lettered_total",
+		Help:      "Messages sent to the dead-letter queue.",
+	})
+)
+
+// RegisterQueueDepth expõe o número de mensagens prontas numa fila, consultado
+// a cada coleta; se a consulta falhar, a série é omitida
+func RegisterQueueDepth(queueName string, depth func() (int, error)) {
+	prometheus.MustRegister(&queueDepthCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "queue", "messages"),
+			"Messages ready for delivery in the queue.",
+			nil, prometheus.Labels{"queue": queueName},
+		),
+		depth: depth,
+	})
+}
+
+// queueDepthCollector consulta a profundidade da fila no momento da coleta
+type queueDepthCollector struct {
+	desc  *prometheus.Desc
+	depth func() (int, error)
+}
+
+// Describe implementa prometheus.Collector
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implementa prometheus.Collector
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	n, err := c.depth()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
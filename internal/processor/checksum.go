@@ -0,0 +1,105 @@
+package processor
+
+// Importações necessárias para a verificação de integridade das fontes
+import (
+	"crypto/md5"               // Para verificar checksums MD5
+	"crypto/sha256"            // Para o hash registrado de toda fonte
+	"encoding/hex"             // Para codificar os hashes
+	"fmt"                      // Para formatação de strings
+	"hash"                     // Para a interface comum dos hashes
+	"io"                       // Para ler o arquivo
+	"ms-videos/internal/queue" // Para a mensagem, o SourceInfo e erros permanentes
+	"os"                       // Para abrir o arquivo
+)
+
+// sourceDigest calcula o tamanho e os hashes da fonte enquanto ela é gravada
+// O SHA-256 é sempre calculado, para registro; o MD5 só quando a mensagem o pede
+type sourceDigest struct {
+	sha256 hash.Hash
+	md5    hash.Hash // nil quando a mensagem não traz um checksum MD5
+	size   int64     // Bytes processados
+	done   bool      // Se o fetcher calculou os hashes durante o download
+}
+
+// newSourceDigest prepara os hashes necessários para a mensagem
+func newSourceDigest(msg queue.VideoMessage) *sourceDigest {
+	d := &sourceDigest{sha256: sha256.New()}
+	if algorithm, _, err := queue.ParseChecksum(msg.Checksum); err == nil && algorithm == queue.ChecksumMD5 {
+		d.md5 = md5.New()
+	}
+	return d
+}
+
+// reset recomeça os hashes, no início de cada tentativa de download
+func (d *sourceDigest) reset() {
+	d.sha256.Reset()
+	if d.md5 != nil {
+		d.md5.Reset()
+	}
+	d.size = 0
+	d.done = false
+}
+
+// Write alimenta os hashes; nunca falha
+func (d *sourceDigest) Write(p []byte) (int, error) {
+	d.sha256.Write(p)
+	if d.md5 != nil {
+		d.md5.Write(p)
+	}
+	d.size += int64(len(p))
+	return len(p), nil
+}
+
+// hashFile alimenta os hashes com os primeiros n bytes do arquivo (n < 0 = todo o arquivo)
+// Usado ao retomar um download, para o que já estava no disco, e para fontes
+// que o fetcher não leu (como file://)
+func (d *sourceDigest) hashFile(path string, n int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer f.Close()
+
+	if n < 0 {
+		_, err = io.Copy(d, f)
+	} else {
+		_, err = io.CopyN(d, f, n)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hash source: %w", err)
+	}
+	return nil
+}
+
+// info retorna os hashes e o tamanho calculados
+func (d *sourceDigest) info() *queue.SourceInfo {
+	info := &queue.SourceInfo{SHA256: hex.EncodeToString(d.sha256.Sum(nil)), Size: d.size}
+	if d.md5 != nil {
+		info.MD5 = hex.EncodeToString(d.md5.Sum(nil))
+	}
+	return info
+}
+
+// verifySource confere o tamanho e o checksum esperados pela mensagem
+// Uma diferença é um erro permanente: a fonte está corrompida ou não é a esperada
+func verifySource(msg queue.VideoMessage, info *queue.SourceInfo) error {
+	if msg.Size > 0 && info.Size != msg.Size {
+		return queue.Permanent(fmt.Errorf("source size mismatch: expected %d bytes, got %d", msg.Size, info.Size))
+	}
+	if msg.Checksum == "" {
+		return nil
+	}
+
+	algorithm, want, err := queue.ParseChecksum(msg.Checksum)
+	if err != nil {
+		return queue.Permanent(err)
+	}
+	got := info.SHA256
+	if algorithm == queue.ChecksumMD5 {
+		got = info.MD5
+	}
+	if got != want {
+		return queue.Permanent(fmt.Errorf("source checksum mismatch: expected %s %s, got %s", algorithm, want, got))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package processor
+
+// Importações necessárias para executar ferramentas externas
+import (
+	"context" // Para cancelar o processo filho
+	"errors"  // Para identificar timeouts e cancelamentos
+	"fmt"     // Para formatação de strings
+	"os"      // Para o sinal de interrupção
+	"os/exec" // Para execução do ffmpeg/ffprobe
+	"time"    // Para o prazo de encerramento
+)
+
+// killGracePeriod é quanto tempo o ffmpeg tem para encerrar após a interrupção
+// antes de ser morto à força
+const killGracePeriod = 10 * time.Second
+
+// command cria um comando ligado ao contexto
+// Quando ctx é cancelado o processo recebe SIGINT, que permite ao ffmpeg
+// fechar os arquivos de saída; se não encerrar em killGracePeriod, é morto
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		// No Windows não há suporte a os.Interrupt, então matamos direto
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = killGracePeriod
+	return cmd
+}
+
+// withTimeout deriva um contexto com o timeout de uma etapa (0 = sem limite)
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// stageError troca o erro genérico de um processo interrompido pelo motivo real
+// (timeout da etapa, cancelamento do job ou desligamento), mantendo-o acessível via errors.Is
+func stageError(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", cause, err)
+}
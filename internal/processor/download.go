@@ -0,0 +1,576 @@
+package processor
+
+// Importações necessárias para o download das fontes
+import (
+	"context"                    // Para cancelar o download e detectar inatividade
+	"crypto/sha256"              // Para nomear os downloads incompletos
+	"encoding/hex"               // Para codificar o nome dos downloads incompletos
+	"encoding/json"              // Para os validadores salvos junto do download incompleto
+	"errors"                     // Para identificar o tipo de falha
+	"fmt"                        // Para formatação de strings
+	"io"                         // Para operações de entrada/saída
+	"log/slog"                   // Para o logger do download
+	"ms-videos/internal/logging" // Para o logger de cada job
+	"ms-videos/internal/metrics" // Para as métricas de download
+	"ms-videos/internal/queue"   // Para erros permanentes
+	"net"                        // Para o timeout de conexão
+	"net/http"                   // Para downloads HTTP
+	neturl "net/url"             // Para fontes file://
+	"os"                         // Para operações de arquivo
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"strconv"                    // Para interpretar o Content-Range
+	"strings"                    // Para manipulação de strings
+	"time"                       // Para timeouts e atrasos entre tentativas
+)
+
+// Padrões do download (veja DownloadConfig)
+const (
+	defaultConnectTimeout    = 30 * time.Second // Tempo máximo para conectar
+	defaultIdleTimeout       = time.Minute      // Tempo máximo sem receber bytes
+	defaultDownloadRetryWait = 2 * time.Second  // Atraso antes da 1ª nova tentativa
+	maxDownloadRetryWait     = time.Minute      // Atraso máximo entre tentativas
+	partialRetention         = 24 * time.Hour   // Downloads incompletos mais antigos são descartados
+	downloadLogInterval      = 30 * time.Second // Intervalo entre logs de progresso
+)
+
+// Erros das tentativas de download
+var (
+	errIdleTimeout    = errors.New("download idle timeout")                      // A origem parou de enviar bytes por mais que o IdleTimeout
+	errResumeMismatch = errors.New("partial download does not match the source") // O arquivo incompleto precisa ser descartado
+)
+
+// sourceStream é a fonte aberta por uma tentativa de download
+type sourceStream struct {
+	body   io.ReadCloser
+	offset int64       // Posição do primeiro byte de body (0 = fonte inteira, descarta o incompleto)
+	total  int64       // Tamanho total da fonte (-1 = desconhecido)
+	meta   partialMeta // Validadores da fonte, salvos para retomar depois
+}
+
+// opener abre a fonte a partir de offset (0 = desde o início)
+// meta são os validadores salvos quando o download começou; se a fonte mudou,
+// o opener devolve a fonte inteira (offset 0) ou um erro com errResumeMismatch
+type opener func(ctx context.Context, offset int64, meta partialMeta) (*sourceStream, error)
+
+// DownloadConfig controla o download das fontes
+type DownloadConfig struct {
+	ConnectTimeout time.Duration // Tempo máximo para conectar e receber os headers (0 = 30s)
+	IdleTimeout    time.Duration // Tempo máximo sem receber bytes (0 = 1m)
+	MaxBytes       int64         // Tamanho máximo da fonte (0 = sem limite)
+	Retries        int           // Novas tentativas após falhas transitórias (0 = nenhuma)
+	RetryDelay     time.Duration // Atraso antes da 1ª nova tentativa, dobra a cada uma (0 = 2s)
+	// PartialDir guarda os downloads incompletos entre tentativas e entregas
+	// da mesma mensagem ("" = <diretório temporário>/ms-videos-partial)
+	PartialDir string
+	// FileRoots são os diretórios (volumes montados) de onde fontes file:// podem
+	// ser lidas; links simbólicos são resolvidos antes da verificação
+	FileRoots []string
+	// S3Buckets são os buckets de onde fontes s3:// podem ser lidas com as
+	// credenciais do armazenamento ("*" = qualquer um; vazio = nenhum)
+	S3Buckets []string
+	// Policy restringe as URLs e os endereços de onde as fontes são baixadas
+	Policy URLPolicy
+}
+
+// withDefaults preenche os valores não configurados
+func (c DownloadConfig) withDefaults() DownloadConfig {
+	if c.ConnectTimeout <= 0 {
+		c.ConnectTimeout = defaultConnectTimeout
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultIdleTimeout
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = defaultDownloadRetryWait
+	}
+	if c.PartialDir == "" {
+		c.PartialDir = filepath.Join(os.TempDir(), "ms-videos-partial")
+	}
+	return c
+}
+
+// newHTTPClient cria o cliente HTTP dos downloads, com timeouts de conexão,
+// TLS e headers; o tempo total fica a cargo do timeout da etapa
+// Cada conexão e cada redirecionamento passam pela política de URLs
+// Proxies não são usados: o endereço conferido precisa ser o da origem
+func newHTTPClient(c DownloadConfig, guard *urlGuard) *http.Client {
+	dialer := &net.Dialer{Timeout: c.ConnectTimeout, KeepAlive: 30 * time.Second}
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if err := guard.checkRedirect(req, via); err != nil {
+				return err
+			}
+			stripCredentials(req, via[0])
+			return nil
+		},
+		Transport: &http.Transport{
+			DialContext:           guard.dialContext(dialer),
+			TLSHandshakeTimeout:   c.ConnectTimeout,
+			ResponseHeaderTimeout: c.ConnectTimeout,
+			IdleConnTimeout:       90 * time.Second,
+			ForceAttemptHTTP2:     true,
+		},
+	}
+}
+
+// partialMeta são os validadores da fonte salvos junto do download incompleto
+// Retomar só é seguro se a fonte não mudou desde a primeira tentativa
+type partialMeta struct {
+	URL          string `json:"url"`                     // Fonte do download
+	ETag         string `json:"etag,omitempty"`          // ETag da resposta original
+	LastModified string `json:"last_modified,omitempty"` // Last-Modified da resposta original
+}
+
+// validator retorna o valor para o header If-Range ("" se não houver um forte)
+func (m partialMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// isPermanentStatus indica se um status HTTP da origem não vai mudar numa nova
+// tentativa: erros 4xx, exceto timeout (408) e rate limit (429)
+func isPermanentStatus(status int) bool {
+	if status == http.StatusRequestTimeout || status == http.StatusTooManyRequests {
+		return false
+	}
+	return status >= 400 && status < 500
+}
+
+// downloadVideo obtém a fonte do job com o Fetcher do esquema da URL
+// Retorna o caminho local da fonte (tempDir/source/<filename>, ou o próprio
+// arquivo no caso de fontes file://) e o seu hash e tamanho, já conferidos
+// com o checksum e o tamanho esperados pela mensagem
+func (vp *VideoProcessor) downloadVideo(ctx context.Context, msg queue.VideoMessage, tempDir string) (string, *queue.SourceInfo, error) {
+	u, err := neturl.Parse(msg.URL)
+	if err != nil {
+		return "", nil, queue.Permanent(fmt.Errorf("invalid video URL: %w", err))
+	}
+	fetcher, ok := vp.fetchers[strings.ToLower(u.Scheme)]
+	if !ok {
+		return "", nil, queue.Permanent(fmt.Errorf("unsupported source URL scheme %q", u.Scheme))
+	}
+
+	dst, err := sourcePath(tempDir, msg.Filename)
+	if err != nil {
+		return "", nil, err
+	}
+	digest := newSourceDigest(msg)
+	path, err := fetcher.Fetch(ctx, Source{URL: u, Msg: msg, Dst: dst, digest: digest})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Fontes que não passaram pelo download retomável são lidas aqui
+	if !digest.done {
+		digest.reset()
+		if err := digest.hashFile(path, -1); err != nil {
+			return "", nil, err
+		}
+	}
+	info := digest.info()
+	if err := verifySource(msg, info); err != nil {
+		return "", nil, err
+	}
+	logging.FromContext(ctx).Info("Verified source", "sha256", info.SHA256, "bytes", info.Size,
+		"expected_checksum", msg.Checksum != "", "expected_size", msg.Size > 0)
+	return path, info, nil
+}
+
+// fetchResumable baixa a fonte para src.Dst com as tentativas abertas por open
+// Falhas transitórias são re-tentadas com atraso exponencial, retomando de onde
+// pararam quando a origem permite; o arquivo incompleto é mantido em PartialDir
+// para que uma nova entrega da mesma mensagem também continue de onde parou
+func (vp *VideoProcessor) fetchResumable(ctx context.Context, src Source, open opener) (string, error) {
+	cfg := vp.config.Download
+	logger := logging.FromContext(ctx)
+	logger.Info("Downloading video", "url", logging.Redact(src.Msg.URL), "max_bytes", cfg.MaxBytes)
+
+	if err := os.MkdirAll(cfg.PartialDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create partial downloads directory: %w", err)
+	}
+	sweepPartials(cfg.PartialDir, logger)
+
+	// O nome do arquivo incompleto depende só do job e da fonte
+	sum := sha256.Sum256([]byte(src.Msg.ID + "\n" + src.Msg.URL))
+	partial := filepath.Join(cfg.PartialDir, hex.EncodeToString(sum[:16])+".part")
+	metaPath := partial + ".json"
+
+	started := time.Now()
+	delay := cfg.RetryDelay
+	var size, received int64 // Tamanho final e bytes recebidos nesta entrega
+	for attempt := 0; ; attempt++ {
+		var err error
+		size, err = vp.downloadAttempt(ctx, src, open, partial, metaPath, &received, logger)
+		if err == nil {
+			break
+		}
+		if queue.IsPermanent(err) {
+			os.Remove(partial)
+			os.Remove(metaPath)
+			return "", err
+		}
+		if ctx.Err() != nil || attempt >= cfg.Retries {
+			return "", err // O arquivo incompleto fica para a próxima entrega
+		}
+
+		logger.Warn("Download failed, retrying", "attempt", attempt+1, "max_attempts", cfg.Retries+1,
+			"delay", delay.String(), "error", err)
+		metrics.DownloadRetries.Inc()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", err
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxDownloadRetryWait)
+	}
+
+	if err := moveFile(partial, src.Dst); err != nil {
+		return "", fmt.Errorf("failed to move downloaded video: %w", err)
+	}
+	os.Remove(metaPath)
+
+	// A taxa considera apenas o que foi recebido nesta entrega, não o que foi retomado
+	elapsed := time.Since(started)
+	rate := float64(received) / elapsed.Seconds()
+	metrics.DownloadThroughput.Observe(rate)
+	logger.Info("Video downloaded successfully", "path", src.Dst, "bytes", size, "received_bytes", received,
+		"elapsed", elapsed.String(), "mib_per_second", rate/(1<<20))
+	return src.Dst, nil
+}
+
+// sourcePath retorna onde a fonte do job é gravada: tempDir/source/<filename>
+// O subdiretório evita colisões com as saídas do job (como "hls"), e o nome é
+// conferido de novo para que nunca saia dele
+func sourcePath(tempDir, filename string) (string, error) {
+	if err := queue.ValidateFilename(filename); err != nil || !filepath.IsLocal(filename) {
+		return "", queue.Permanent(fmt.Errorf("unsafe source filename %q", filename))
+	}
+	dir := filepath.Join(tempDir, "source")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create source directory: %w", err)
+	}
+	return filepath.Join(dir, filename), nil
+}
+
+// fetchHTTP baixa fontes http e https, com as credenciais do job (SourceAuth)
+// A URL é conferida pela política antes de qualquer conexão; os endereços
+// resolvidos e os redirecionamentos são conferidos pelo cliente HTTP
+func (vp *VideoProcessor) fetchHTTP(ctx context.Context, src Source) (string, error) {
+	if err := vp.guard.checkURL(src.URL); err != nil {
+		return "", queue.Permanent(err)
+	}
+	return vp.fetchResumable(ctx, src, func(ctx context.Context, offset int64, meta partialMeta) (*sourceStream, error) {
+		return vp.openHTTP(ctx, src, offset, meta)
+	})
+}
+
+// openHTTP requisita a fonte, pedindo com Range apenas o que falta a partir de offset
+// If-Range garante que a origem devolve a fonte inteira se ela mudou
+func (vp *VideoProcessor) openHTTP(ctx context.Context, src Source, offset int64, meta partialMeta) (*sourceStream, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL.String(), nil)
+	if err != nil {
+		return nil, queue.Permanent(fmt.Errorf("invalid video URL: %w", err))
+	}
+	if auth := src.Msg.Auth; auth != nil {
+		for name, value := range auth.Headers {
+			req.Header.Set(name, value)
+		}
+		if auth.Username != "" {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
+	}
+
+	resp, err := vp.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errURLPolicy) {
+			return nil, queue.Permanent(fmt.Errorf("failed to download video: %w", err))
+		}
+		return nil, fmt.Errorf("failed to download video: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &sourceStream{
+			body:  resp.Body,
+			total: resp.ContentLength, // -1 se a origem não informou
+			meta:  partialMeta{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")},
+		}, nil
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected Content-Range %q for offset %d: %w",
+				resp.Header.Get("Content-Range"), offset, errResumeMismatch)
+		}
+		return &sourceStream{body: resp.Body, offset: offset, total: size, meta: meta}, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to resume download: status %d: %w", resp.StatusCode, errResumeMismatch)
+	}
+
+	resp.Body.Close()
+	err = fmt.Errorf("failed to download video: status %d", resp.StatusCode)
+	if isPermanentStatus(resp.StatusCode) {
+		return nil, queue.Permanent(err)
+	}
+	return nil, err
+}
+
+// stripCredentials remove os headers do job de um redirecionamento para outro
+// host ou de https para http, onde seriam enviados sem criptografia
+// O cliente HTTP já remove Authorization e Cookie quando o host muda, mas não
+// headers próprios (como X-Api-Key) nem em redirecionamentos para http no mesmo
+// host; só os headers do próprio download são mantidos
+func stripCredentials(req *http.Request, original *http.Request) {
+	downgrade := strings.EqualFold(original.URL.Scheme, "https") && !strings.EqualFold(req.URL.Scheme, "https")
+	if strings.EqualFold(req.URL.Host, original.URL.Host) && !downgrade {
+		return
+	}
+	for name := range req.Header {
+		if name != "Range" && name != "If-Range" {
+			req.Header.Del(name)
+		}
+	}
+}
+
+// downloadAttempt faz uma tentativa de download para partial, retomando do
+// tamanho atual do arquivo quando a origem permite e a fonte não mudou
+// Retorna o tamanho final do arquivo; received acumula os bytes recebidos
+func (vp *VideoProcessor) downloadAttempt(ctx context.Context, src Source, open opener, partial, metaPath string, received *int64, logger *slog.Logger) (int64, error) {
+	cfg := vp.config.Download
+
+	// Retoma apenas se o arquivo incompleto é desta fonte e tem um validador
+	var offset int64
+	meta := loadPartialMeta(metaPath)
+	if info, err := os.Stat(partial); err == nil && meta.URL == src.Msg.URL && meta.validator() != "" {
+		offset = info.Size()
+	}
+
+	// A inatividade cancela a leitura com errIdleTimeout como causa
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stream, err := open(ctx, offset, meta)
+	if errors.Is(err, errResumeMismatch) {
+		os.Remove(partial) // Recomeça do zero na próxima tentativa
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer stream.body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if stream.offset == 0 {
+		// Fonte inteira: a origem não permite retomar ou a fonte mudou
+		if offset > 0 {
+			logger.Info("Source does not support resuming or has changed, restarting download", "discarded_bytes", offset)
+		}
+		flags |= os.O_TRUNC
+		stream.meta.URL = src.Msg.URL
+		if err := savePartialMeta(metaPath, stream.meta); err != nil {
+			return 0, err
+		}
+	} else {
+		logger.Info("Resuming download", "offset", stream.offset)
+	}
+	offset = stream.offset
+	total := stream.total
+
+	// Os hashes recomeçam a cada tentativa; ao retomar, a parte já baixada é lida do disco
+	digest := src.digest
+	if digest != nil {
+		digest.reset()
+		if offset > 0 {
+			if err := digest.hashFile(partial, offset); err != nil {
+				os.Remove(partial) // Recomeça do zero na próxima tentativa
+				return 0, err
+			}
+		}
+	}
+
+	// Rejeita antes de baixar qualquer byte se o tamanho anunciado passa do limite
+	if cfg.MaxBytes > 0 && total > cfg.MaxBytes {
+		return 0, queue.Permanent(fmt.Errorf("source is %d bytes, larger than the maximum of %d bytes", total, cfg.MaxBytes))
+	}
+	// Ou se difere do tamanho esperado pela mensagem
+	expected := src.Msg.Size
+	if expected > 0 && total >= 0 && total != expected {
+		return 0, queue.Permanent(fmt.Errorf("source size mismatch: expected %d bytes, origin reports %d", expected, total))
+	}
+
+	file, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	body := &idleReader{r: stream.body, timeout: cfg.IdleTimeout}
+	body.timer = time.AfterFunc(cfg.IdleTimeout, func() { cancel(errIdleTimeout) })
+	defer body.timer.Stop()
+
+	// Copia em blocos para aplicar o limite durante o streaming e registrar o progresso
+	buf := make([]byte, 256<<10)
+	written := offset
+	lastLog := time.Now()
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if cfg.MaxBytes > 0 && written+int64(n) > cfg.MaxBytes {
+				return 0, queue.Permanent(fmt.Errorf("source is larger than the maximum of %d bytes", cfg.MaxBytes))
+			}
+			if expected > 0 && written+int64(n) > expected {
+				return 0, queue.Permanent(fmt.Errorf("source size mismatch: larger than the expected %d bytes", expected))
+			}
+			if _, err := file.Write(buf[:n]); err != nil {
+				return 0, fmt.Errorf("failed to save video: %w", err)
+			}
+			if digest != nil {
+				digest.Write(buf[:n])
+			}
+			written += int64(n)
+			*received += int64(n)
+			metrics.DownloadedBytes.Add(float64(n))
+		}
+		if time.Since(lastLog) >= downloadLogInterval {
+			logger.Info("Download progress", "bytes", written, "total_bytes", total)
+			lastLog = time.Now()
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			if errors.Is(context.Cause(ctx), errIdleTimeout) {
+				readErr = fmt.Errorf("no data received for %s: %w", cfg.IdleTimeout, errIdleTimeout)
+			}
+			return 0, fmt.Errorf("failed to download video after %d bytes: %w", written, readErr)
+		}
+	}
+
+	if total >= 0 && written != total {
+		return 0, fmt.Errorf("download ended after %d of %d bytes: %w", written, total, io.ErrUnexpectedEOF)
+	}
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to save video: %w", err)
+	}
+	if digest != nil {
+		digest.done = true
+	}
+	return written, nil
+}
+
+// idleReader cancela o download se nenhum byte chegar dentro de timeout
+type idleReader struct {
+	r       io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// parseContentRange interpreta "bytes <início>-<fim>/<total>"
+// total é -1 quando a origem não informa o tamanho ("*")
+func parseContentRange(v string) (start, total int64, ok bool) {
+	rng, found := strings.CutPrefix(v, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, size, found := strings.Cut(rng, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if size == "*" {
+		return start, -1, true
+	}
+	total, err = strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// loadPartialMeta lê os validadores de um download incompleto (vazio se não houver)
+func loadPartialMeta(path string) partialMeta {
+	var meta partialMeta
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// savePartialMeta grava os validadores de um download incompleto
+func savePartialMeta(path string, meta partialMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal download metadata: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save download metadata: %w", err)
+	}
+	return nil
+}
+
+// sweepPartials remove downloads incompletos abandonados há mais de partialRetention
+func sweepPartials(dir string, logger *slog.Logger) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-partialRetention)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+			logger.Debug("Removed stale partial download", "file", entry.Name())
+		}
+	}
+}
+
+// moveFile move src para dst, copiando quando estão em sistemas de arquivos diferentes
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,142 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"ms-videos/internal/queue"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in    string
+		start int64
+		total int64
+		ok    bool
+	}{
+		{"bytes 0-99/100", 0, 100, true},
+		{"bytes 1000-1999/5000", 1000, 5000, true},
+		{"bytes 500-999/*", 500, -1, true},
+		{"bytes */5000", 0, 0, false}, // Resposta 416, sem intervalo
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 0-99", 0, 0, false},
+		{"bytes x-99/100", 0, 0, false},
+		{"bytes 0-99/abc", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.in)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.in, start, total, ok, tt.start, tt.total, tt.ok)
+		}
+	}
+}
+
+func TestStripCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		keep     bool
+	}{
+		{"same host", "https://cdn.example.com/a.mp4", "https://cdn.example.com/b.mp4", true},
+		{"http to https upgrade", "http://cdn.example.com/a.mp4", "https://cdn.example.com/a.mp4", true},
+		{"other host", "https://cdn.example.com/a.mp4", "https://storage.example.net/a.mp4", false},
+		{"other port", "https://cdn.example.com/a.mp4", "https://cdn.example.com:8443/a.mp4", false},
+		{"https downgrade", "https://cdn.example.com/a.mp4", "http://cdn.example.com/a.mp4", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := httptest.NewRequest(http.MethodGet, tt.from, nil)
+			req := httptest.NewRequest(http.MethodGet, tt.to, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("X-Api-Key", "secret")
+			req.Header.Set("Range", "bytes=100-")
+
+			stripCredentials(req, original)
+
+			kept := req.Header.Get("Authorization") != "" && req.Header.Get("X-Api-Key") != ""
+			stripped := req.Header.Get("Authorization") == "" && req.Header.Get("X-Api-Key") == ""
+			if tt.keep && !kept {
+				t.Errorf("credentials were stripped: %v", req.Header)
+			}
+			if !tt.keep && !stripped {
+				t.Errorf("credentials were kept: %v", req.Header)
+			}
+			// Os headers do próprio download sempre seguem
+			if req.Header.Get("Range") != "bytes=100-" {
+				t.Errorf("Range header was stripped")
+			}
+		})
+	}
+}
+
+func TestDownloadVideoVerifiesSource(t *testing.T) {
+	data := bytes.Repeat([]byte("ms-videos "), 10000)
+	sha := sha256.Sum256(data)
+	md := md5.Sum(data)
+	shaHex, mdHex := hex.EncodeToString(sha[:]), hex.EncodeToString(md[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mp4", time.Unix(1700000000, 0), bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	vp := NewVideoProcessor(nil, Config{Download: DownloadConfig{
+		PartialDir: t.TempDir(),
+		Policy:     URLPolicy{AllowPrivateIPs: true}, // O servidor de teste escuta em 127.0.0.1
+	}})
+
+	tests := []struct {
+		name      string
+		checksum  string
+		size      int64
+		permanent bool
+	}{
+		{name: "no expectation"},
+		{name: "sha256 and size", checksum: "sha256:" + shaHex, size: int64(len(data))},
+		{name: "md5 uppercase", checksum: "MD5:" + mdHex},
+		{name: "wrong sha256", checksum: "sha256:" + hex.EncodeToString(make([]byte, 32)), permanent: true},
+		{name: "wrong md5", checksum: "md5:" + hex.EncodeToString(make([]byte, 16)), permanent: true},
+		{name: "origin reports another size", size: 10, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := queue.VideoMessage{
+				ID:       "checksum-test",
+				URL:      srv.URL + "/video.mp4",
+				Filename: "video.mp4",
+				Checksum: tt.checksum,
+				Size:     tt.size,
+			}
+			path, info, err := vp.downloadVideo(context.Background(), msg, t.TempDir())
+			if tt.permanent {
+				if !queue.IsPermanent(err) {
+					t.Fatalf("expected a permanent error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// O SHA-256 é registrado mesmo sem checksum esperado
+			if info.SHA256 != shaHex || info.Size != int64(len(data)) {
+				t.Errorf("source info = %+v, want sha256 %s and size %d", info, shaHex, len(data))
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+				t.Errorf("downloaded file differs from the source")
+			}
+		})
+	}
+}
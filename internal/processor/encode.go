@@ -0,0 +1,301 @@
+package processor
+
+// Importações necessárias para a geração das variantes HLS com ffmpeg
+import (
+	"context"                    // Para cancelar os encodes irmãos quando um falha
+	"fmt"                        // Para formatação de strings
+	"io"                         // Para descartar a saída de progresso restante
+	"math"                       // Para cálculo do tamanho do GOP
+	"ms-videos/internal/logging" // Para o logger de cada variante
+	"ms-videos/internal/metrics" // Para as métricas de encoding
+	"os"                         // Para operações do sistema operacional
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"strconv"                    // Para conversão de números em argumentos do ffmpeg
+	"strings"                    // Para montagem do filtergraph
+	"sync"                       // Para coordenar os encodes paralelos
+	"time"                       // Para a duração usada no cálculo do progresso
+)
+
+// segmentSeconds é a duração alvo de cada segmento HLS
+const segmentSeconds = 10
+
+// Modos de encoding das variantes
+const (
+	// EncodeSinglePass gera todas as variantes com uma única execução do ffmpeg,
+	// decodificando a fonte uma só vez (padrão)
+	EncodeSinglePass = "single"
+	// EncodePerRendition executa um ffmpeg por variante, vários ao mesmo tempo
+	// dentro do orçamento de threads configurado
+	EncodePerRendition = "per_rendition"
+)
+
+// encodeRenditions gera as variantes HLS do job conforme o modo configurado
+// Em ambos os modos o GOP é fixo e os keyframes são forçados nas fronteiras
+// dos segmentos, então todas as variantes têm a mesma temporização
+func (vp *VideoProcessor) encodeRenditions(ctx context.Context, j *job) error {
+	if vp.config.EncodeMode == EncodePerRendition {
+		return vp.encodeParallel(ctx, j)
+	}
+
+	ctx = logging.With(ctx, "rendition", metrics.RenditionAll)
+	logging.FromContext(ctx).Info("Encoding all renditions in a single pass",
+		"renditions", len(j.rungs), "profile", j.profile.Name, "threads", vp.config.Threads)
+	if err := vp.encodeAll(ctx, j); err != nil {
+		return fmt.Errorf("failed to encode renditions: %w", err)
+	}
+	return nil
+}
+
+// encodeParallel executa um ffmpeg por variante, com até MaxParallelEncodes
+// processos simultâneos dividindo o orçamento de threads entre si
+// A primeira falha cancela as variantes que ainda estão em andamento
+func (vp *VideoProcessor) encodeParallel(ctx context.Context, j *job) error {
+	parallel := vp.config.MaxParallelEncodes
+	if parallel <= 0 || parallel > len(j.rungs) {
+		parallel = len(j.rungs)
+	}
+	if parallel > vp.config.Threads {
+		parallel = vp.config.Threads
+	}
+	threads := vp.config.Threads / parallel
+
+	logging.FromContext(ctx).Info("Encoding renditions in parallel",
+		"renditions", len(j.rungs), "parallel", parallel, "threads_each", threads, "profile", j.profile.Name)
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, parallel) // Semáforo que limita os ffmpeg simultâneos
+	for _, rung := range j.rungs {
+		wg.Add(1)
+		go func(rung Rung) {
+			defer wg.Done()
+
+			// Aguarda uma vaga, desistindo se alguma variante já falhou
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			rctx := logging.With(ctx, "rendition", rung.Name)
+			logging.FromContext(rctx).Info("Encoding rendition")
+			if err := vp.processResolution(rctx, j, rung, threads); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("failed to process %s resolution: %w", rung.Name, err)
+					cancel() // Interrompe as variantes irmãs
+				})
+			}
+		}(rung)
+	}
+	wg.Wait()
+
+	// Variantes que desistiram por cancelamento do job não registram erro: sem
+	// esta verificação, o job seguiria para as playlists com variantes faltando
+	if firstErr == nil && parent.Err() != nil {
+		return context.Cause(parent)
+	}
+	return firstErr
+}
+
+// encodeAll gera todas as variantes com um único ffmpeg: a fonte é decodificada
+// uma vez, dividida com o filtro split e cada ramo é escalado e codificado
+// separadamente; -var_stream_map associa cada ramo ao seu diretório
+func (vp *VideoProcessor) encodeAll(ctx context.Context, j *job) error {
+	hlsDir := filepath.Join(j.tempDir, "hls")
+	hasAudio := j.media.HasAudio()
+
+	// Filtergraph: [0:v]split=N[s0][s1]...;[s0]scale=W:H,setsar=1[v0];...
+	var graph strings.Builder
+	fmt.Fprintf(&graph, "[0:v]split=%d", len(j.rungs))
+	for i := range j.rungs {
+		fmt.Fprintf(&graph, "[s%d]", i)
+	}
+	for i, rung := range j.rungs {
+		width, height := outputSize(j.media, j.profile, rung)
+		fmt.Fprintf(&graph, ";[s%d]scale=%d:%d,setsar=1[v%d]", i, width, height, i)
+
+		// Create output directory for this resolution
+		if err := os.MkdirAll(filepath.Join(hlsDir, rung.Name), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	args := []string{"-i", j.sourcePath, "-filter_complex", graph.String()}
+
+	var streamMap []string
+	for i, rung := range j.rungs {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i))
+		args = append(args, videoCodecArgs(j.profile, rung, fmt.Sprintf(":v:%d", i))...)
+
+		entry := fmt.Sprintf("v:%d", i)
+		if hasAudio {
+			// Cada variante leva sua própria cópia do áudio, como no modo por variante
+			args = append(args, "-map", "0:a:0",
+				fmt.Sprintf("-c:a:%d", i), "aac",
+				fmt.Sprintf("-b:a:%d", i), strconv.Itoa(j.profile.AudioBitrate),
+			)
+			entry += fmt.Sprintf(",a:%d", i)
+		}
+		streamMap = append(streamMap, entry+",name:"+rung.Name)
+	}
+
+	args = append(args, "-preset:v", j.profile.Preset, "-threads", strconv.Itoa(vp.config.Threads))
+	args = append(args, keyframeArgs(j.media)...)
+	args = append(args,
+		"-hls_time", strconv.Itoa(segmentSeconds),
+		"-hls_list_size", "0", // Keep all segments in playlist
+		"-hls_segment_filename", filepath.Join(hlsDir, "%v", "segment_%03d.ts"),
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-f", "hls",
+		filepath.Join(hlsDir, "%v", "playlist.m3u8"),
+	)
+
+	// Todas as variantes saem do mesmo processo, então compartilham o progresso
+	names := make([]string, len(j.rungs))
+	for i, rung := range j.rungs {
+		names[i] = rung.Name
+	}
+	started := time.Now()
+	if err := runFFmpeg(ctx, args, j.media.Duration, vp.progressReporter(ctx, j, names...)); err != nil {
+		return err
+	}
+	observeEncode(metrics.RenditionAll, j.media, started)
+
+	logging.FromContext(ctx).Info("Encoded all renditions", "renditions", len(j.rungs), "elapsed", time.Since(started).String())
+	return nil
+}
+
+// processResolution gera uma única variante com uma execução dedicada do ffmpeg
+// limitada a threads threads; o processo é encerrado se ctx for cancelado
+func (vp *VideoProcessor) processResolution(ctx context.Context, j *job, rung Rung, threads int) error {
+	width, height := outputSize(j.media, j.profile, rung)
+
+	// Create output directory for this resolution
+	outputDir := filepath.Join(j.tempDir, "hls", rung.Name)
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := []string{
+		"-i", j.sourcePath,
+		// ffmpeg autorotates the input, so the explicit size follows the display
+		// geometry; setsar=1 makes the output use square pixels
+		"-vf", fmt.Sprintf("scale=%d:%d,setsar=1", width, height),
+		"-preset:v", j.profile.Preset,
+		"-threads", strconv.Itoa(threads),
+	}
+	args = append(args, videoCodecArgs(j.profile, rung, ":v")...)
+	args = append(args, keyframeArgs(j.media)...)
+	args = append(args,
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(j.profile.AudioBitrate),
+		"-hls_time", strconv.Itoa(segmentSeconds),
+		"-hls_list_size", "0", // Keep all segments in playlist
+		"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"),
+		"-f", "hls",
+		filepath.Join(outputDir, "playlist.m3u8"),
+	)
+
+	started := time.Now()
+	if err := runFFmpeg(ctx, args, j.media.Duration, vp.progressReporter(ctx, j, rung.Name)); err != nil {
+		return fmt.Errorf("ffmpeg failed for %s: %w", rung.Name, err)
+	}
+	observeEncode(rung.Name, j.media, started)
+
+	logging.FromContext(ctx).Info("Encoded rendition", "width", width, "height", height, "elapsed", time.Since(started).String())
+	return nil
+}
+
+// observeEncode registra a duração e a velocidade de um encode concluído
+// A velocidade é a duração da fonte dividida pelo tempo gasto
+func observeEncode(rendition string, media *MediaInfo, started time.Time) {
+	elapsed := time.Since(started)
+	observeStage(metrics.StageEncode, rendition, started)
+	if elapsed > 0 && media.Duration > 0 {
+		metrics.EncodeSpeed.WithLabelValues(rendition).Observe(media.Duration.Seconds() / elapsed.Seconds())
+	}
+}
+
+// videoCodecArgs monta as opções do libx264 de um degrau
+// spec é o especificador de stream (":v" ou ":v:N" no modo de passada única)
+func videoCodecArgs(profile *Profile, rung Rung, spec string) []string {
+	args := []string{
+		"-c" + spec, "libx264",
+		"-profile" + spec, rung.Profile,
+	}
+	if rung.Level != "" {
+		args = append(args, "-level"+spec, rung.Level)
+	}
+	return append(args,
+		"-b"+spec, strconv.Itoa(rung.VideoBitrate),
+		"-maxrate"+spec, strconv.Itoa(rung.MaxRate),
+		"-bufsize"+spec, strconv.Itoa(rung.BufSize),
+	)
+}
+
+// keyframeArgs fixa o GOP no tamanho de um segmento e força keyframes nas
+// fronteiras dos segmentos, sem keyframes extras em trocas de cena
+// Assim todas as variantes são cortadas exatamente nos mesmos instantes
+func keyframeArgs(media *MediaInfo) []string {
+	fps := media.FrameRate
+	if fps <= 0 {
+		fps = 30
+	}
+	gop := strconv.Itoa(int(math.Round(fps * segmentSeconds)))
+
+	return []string{
+		"-g", gop,
+		"-keyint_min", gop,
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentSeconds),
+	}
+}
+
+// stderrTailSize é quanto do final do stderr do ffmpeg é anexado aos erros
+const stderrTailSize = 4096
+
+// runFFmpeg executa o ffmpeg com os argumentos fornecidos
+// O progresso é lido de -progress e repassado a onProgress; duration é a
+// duração da fonte, usada no cálculo do percentual
+// Em caso de falha, o final do stderr do ffmpeg é incluído no erro
+// O processo é interrompido se ctx for cancelado
+func runFFmpeg(ctx context.Context, args []string, duration time.Duration, onProgress func(Progress)) error {
+	args = append([]string{"-hide_banner", "-nostats", "-progress", "pipe:1"}, args...)
+	cmd := command(ctx, "ffmpeg", args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open ffmpeg progress pipe: %w", err)
+	}
+	stderr := newTailBuffer(stderrTailSize)
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	if err := parseProgress(stdout, duration, onProgress); err != nil {
+		logging.FromContext(ctx).Warn("Failed to read ffmpeg progress", "error", err)
+		io.Copy(io.Discard, stdout) // Evita que o ffmpeg trave com o pipe cheio
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if tail := stderr.String(); tail != "" {
+			return fmt.Errorf("%w: %s", err, tail)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package processor
+
+// Importações necessárias para publicar eventos do ciclo de vida dos jobs
+import (
+	"context"                  // Para limitar o tempo de publicação
+	"errors"                   // Para combinar falhas de vários publishers
+	"fmt"                      // Para formatação de erros
+	"log/slog"                 // Para logging estruturado
+	"ms-videos/internal/queue" // Para a estrutura dos eventos
+	"sync"                     // Para impedir envios depois de Close
+	"time"                     // Para o timeout de publicação
+)
+
+// eventTimeout limita quanto uma publicação de evento pode atrasar o job
+const eventTimeout = 10 * time.Second
+
+// defaultEventBuffer é quantos eventos podem aguardar publicação num AsyncPublisher
+const defaultEventBuffer = 256
+
+// errEventDropped indica um evento de progresso descartado com a fila cheia
+var errEventDropped = errors.New("event buffer is full, progress event dropped")
+
+// EventPublisher recebe os eventos do ciclo de vida dos jobs
+// queue.RabbitMQPublisher implementa esta interface
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, ev queue.JobEvent) error
+}
+
+// MultiPublisher repassa cada evento a vários publishers, na ordem
+// Todos recebem o evento mesmo que algum falhe; os erros são combinados
+type MultiPublisher []EventPublisher
+
+// PublishEvent implementa EventPublisher
+func (m MultiPublisher) PublishEvent(ctx context.Context, ev queue.JobEvent) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.PublishEvent(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AsyncPublisher publica os eventos numa goroutine própria, na ordem em que
+// chegam, para que um broker lento ou fora do ar não atrase os jobs (o
+// progresso do encoding é emitido por quem lê a saída do ffmpeg)
+// Com a fila cheia, eventos de progresso são descartados; os demais esperam
+// por uma vaga enquanto o contexto de quem publica permitir
+type AsyncPublisher struct {
+	next   EventPublisher
+	events chan queue.JobEvent
+	mu     sync.RWMutex // Envios usam RLock; Close usa Lock para fechar o canal
+	closed bool
+	ctx    context.Context // Cancelado quando Close desiste de esperar a fila esvaziar
+	cancel context.CancelFunc
+	done   chan struct{} // Fechado quando a goroutine termina
+}
+
+// NewAsyncPublisher cria o AsyncPublisher e inicia a goroutine que publica em next
+// size é o tamanho da fila (0 = defaultEventBuffer)
+func NewAsyncPublisher(next EventPublisher, size int) *AsyncPublisher {
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &AsyncPublisher{
+		next:   next,
+		events: make(chan queue.JobEvent, size),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// PublishEvent implementa EventPublisher: enfileira o evento sem esperar a publicação
+func (a *AsyncPublisher) PublishEvent(ctx context.Context, ev queue.JobEvent) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return errors.New("event publisher is closed")
+	}
+
+	if ev.Type == queue.EventEncoding {
+		select {
+		case a.events <- ev:
+			return nil
+		default:
+			return errEventDropped
+		}
+	}
+	select {
+	case a.events <- ev:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("event queue is full: %w", ctx.Err())
+	}
+}
+
+// run publica os eventos da fila até ela ser fechada por Close
+func (a *AsyncPublisher) run() {
+	defer close(a.done)
+	for ev := range a.events {
+		ctx, cancel := context.WithTimeout(a.ctx, eventTimeout)
+		if err := a.next.PublishEvent(ctx, ev); err != nil {
+			slog.Warn("Failed to publish event", "job_id", ev.JobID, "event", ev.Type, "error", err)
+		}
+		cancel()
+	}
+}
+
+// Close para de aceitar eventos e espera, por até eventTimeout, que os
+// pendentes sejam publicados; os que restarem depois disso são descartados
+func (a *AsyncPublisher) Close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+	case <-time.After(eventTimeout):
+		slog.Warn("Timed out publishing pending events", "pending", len(a.events))
+		a.cancel()
+		<-a.done
+	}
+	a.cancel()
+}
+
+// emit publica um evento do job, se houver um publisher configurado
+// Falhas de publicação são registradas mas não interrompem o processamento
+// Publishers remotos devem ser envolvidos num AsyncPublisher, para que emit não
+// espere pelo broker
+// O contexto é próprio para que o evento "failed" saia mesmo após um cancelamento
+func (vp *VideoProcessor) emit(ev queue.JobEvent) {
+	if vp.config.Events == nil {
+		return
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	err := vp.config.Events.PublishEvent(ctx, ev)
+	switch {
+	case errors.Is(err, errEventDropped):
+		slog.Debug("Dropped progress event", "job_id", ev.JobID, "rendition", ev.Rendition)
+	case err != nil:
+		slog.Warn("Failed to publish event", "job_id", ev.JobID, "event", ev.Type, "error", err)
+	}
+}
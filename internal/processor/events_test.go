@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ms-videos/internal/queue"
+)
+
+// blockingPublisher só publica depois que release é fechado
+type blockingPublisher struct {
+	release chan struct{}
+	mu      sync.Mutex
+	got     []string
+}
+
+func (b *blockingPublisher) PublishEvent(ctx context.Context, ev queue.JobEvent) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	b.got = append(b.got, ev.Type)
+	b.mu.Unlock()
+	return nil
+}
+
+// Com o broker travado, o progresso é descartado sem bloquear, os eventos
+// terminais esperam por uma vaga e nada sai de ordem
+func TestAsyncPublisherDropsOnlyProgress(t *testing.T) {
+	next := &blockingPublisher{release: make(chan struct{})}
+	a := NewAsyncPublisher(next, 2)
+
+	ctx := context.Background()
+	publish := func(typ string) error {
+		return a.PublishEvent(ctx, queue.JobEvent{Type: typ, JobID: "a"})
+	}
+
+	// O primeiro evento é retirado da fila pela goroutine, que trava no broker
+	if err := publish(queue.EventReceived); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(a.events) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for _, typ := range []string{queue.EventDownloading, queue.EventEncoding} {
+		if err := publish(typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fila cheia: o progresso volta na hora
+	start := time.Now()
+	if err := publish(queue.EventEncoding); !errors.Is(err, errEventDropped) {
+		t.Errorf("expected errEventDropped, got %v", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("dropping a progress event blocked for %v", time.Since(start))
+	}
+
+	// Um evento terminal espera pela vaga até o prazo de quem publica
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := a.PublishEvent(short, queue.JobEvent{Type: queue.EventFailed}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the terminal event to wait for room, got %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- publish(queue.EventCompleted) }()
+
+	close(next.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	want := []string{queue.EventReceived, queue.EventDownloading, queue.EventEncoding, queue.EventCompleted}
+	if len(next.got) != len(want) {
+		t.Fatalf("published %v, want %v", next.got, want)
+	}
+	for i := range want {
+		if next.got[i] != want[i] {
+			t.Errorf("published %v, want %v", next.got, want)
+			break
+		}
+	}
+
+	if err := publish(queue.EventCompleted); err == nil {
+		t.Errorf("expected an error after Close")
+	}
+}
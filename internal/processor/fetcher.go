@@ -0,0 +1,172 @@
+package processor
+
+// Importações necessárias para os fetchers das fontes
+import (
+	"context"                    // Para cancelar os downloads
+	"errors"                     // Para classificar os erros do armazenamento
+	"fmt"                        // Para formatação de strings
+	"ms-videos/internal/logging" // Para o logger do job
+	"ms-videos/internal/queue"   // Para a mensagem do job e erros permanentes
+	"ms-videos/internal/storage" // Para ler fontes do armazenamento
+	"net/http"                   // Para formatar o Last-Modified
+	neturl "net/url"             // Para as URLs das fontes
+	"os"                         // Para fontes locais
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"strings"                    // Para manipulação de strings
+)
+
+// Source é a fonte de um job, entregue ao Fetcher do esquema da URL
+type Source struct {
+	URL *neturl.URL        // URL da fonte, já interpretada
+	Msg queue.VideoMessage // Mensagem do job (ID e credenciais da fonte)
+	Dst string             // Onde gravar a fonte baixada (tempDir/source/<filename>)
+
+	// digest recebe os bytes durante os downloads retomáveis; fontes que não
+	// passam por ele são lidas depois do Fetch para o cálculo dos hashes
+	digest *sourceDigest
+}
+
+// Fetcher obtém a fonte de um job para um esquema de URL
+// Os fetchers embutidos atendem http, https, s3 e file; outros podem ser
+// registrados em Config.Fetchers
+type Fetcher interface {
+	// Fetch grava a fonte em src.Dst e retorna o caminho local da fonte, que
+	// pode ser outro quando ela já está no disco (como em file://)
+	// Erros marcados com queue.Permanent não são re-tentados
+	Fetch(ctx context.Context, src Source) (string, error)
+}
+
+// FetcherFunc permite usar uma função comum como Fetcher
+type FetcherFunc func(ctx context.Context, src Source) (string, error)
+
+// Fetch implementa Fetcher
+func (f FetcherFunc) Fetch(ctx context.Context, src Source) (string, error) {
+	return f(ctx, src)
+}
+
+// newFetchers registra os fetchers embutidos e os de Config.Fetchers, que
+// podem substituí-los
+func (vp *VideoProcessor) newFetchers() map[string]Fetcher {
+	fetchers := map[string]Fetcher{
+		"http":  FetcherFunc(vp.fetchHTTP),
+		"https": FetcherFunc(vp.fetchHTTP),
+		"s3":    FetcherFunc(vp.fetchS3),
+		"file":  FetcherFunc(vp.fetchFile),
+	}
+	for scheme, fetcher := range vp.config.Fetchers {
+		fetchers[strings.ToLower(scheme)] = fetcher
+	}
+	return fetchers
+}
+
+// fetchS3 baixa fontes s3://<bucket>/<chave> com as credenciais do armazenamento,
+// sem precisar de URLs assinadas; só os buckets de DownloadConfig.S3Buckets são aceitos
+// Usa as mesmas tentativas e a mesma retomada dos downloads HTTP
+func (vp *VideoProcessor) fetchS3(ctx context.Context, src Source) (string, error) {
+	reader, ok := vp.storage.(storage.ObjectReader)
+	if !ok {
+		return "", queue.Permanent(fmt.Errorf("s3 sources require the %s storage backend", storage.BackendMinIO))
+	}
+	bucket, key := src.URL.Host, strings.TrimPrefix(src.URL.Path, "/")
+	if bucket == "" || key == "" {
+		return "", queue.Permanent(fmt.Errorf("invalid s3 URL %q: expected s3://<bucket>/<key>", src.Msg.URL))
+	}
+	if !vp.s3BucketAllowed(bucket) {
+		return "", queue.Permanent(fmt.Errorf("%w: bucket %q is not allowed", errURLPolicy, bucket))
+	}
+
+	return vp.fetchResumable(ctx, src, func(ctx context.Context, offset int64, meta partialMeta) (*sourceStream, error) {
+		info, err := reader.StatObject(ctx, bucket, key)
+		if err != nil {
+			return nil, objectError(err)
+		}
+		// Retoma só se o objeto é o mesmo do início do download
+		if info.ETag != meta.ETag || offset >= info.Size {
+			offset = 0
+		}
+		body, err := reader.OpenObject(ctx, bucket, key, offset, info.ETag)
+		if err != nil {
+			return nil, objectError(err)
+		}
+		return &sourceStream{
+			body:   body,
+			offset: offset,
+			total:  info.Size,
+			meta:   partialMeta{ETag: info.ETag, LastModified: info.LastModified.UTC().Format(http.TimeFormat)},
+		}, nil
+	})
+}
+
+// s3BucketAllowed indica se fontes podem ser lidas do bucket
+func (vp *VideoProcessor) s3BucketAllowed(bucket string) bool {
+	for _, allowed := range vp.config.Download.S3Buckets {
+		if allowed == "*" || allowed == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// objectError marca como permanentes os erros de objetos inexistentes ou sem acesso
+func objectError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrAccessDenied) {
+		return queue.Permanent(fmt.Errorf("failed to download video: %w", err))
+	}
+	return fmt.Errorf("failed to download video: %w", err)
+}
+
+// fetchFile resolve uma fonte file:// (como um volume montado) para o caminho
+// local, sem copiar o arquivo
+// Arquivos inexistentes, grandes demais ou fora de DownloadConfig.FileRoots
+// (sem AllowFileURLs) são erros permanentes
+func (vp *VideoProcessor) fetchFile(ctx context.Context, src Source) (string, error) {
+	if !vp.config.AllowFileURLs && len(vp.config.Download.FileRoots) == 0 {
+		return "", queue.Permanent(fmt.Errorf("file URLs are not allowed"))
+	}
+	u := src.URL
+	if u.Path == "" || (u.Host != "" && u.Host != "localhost") {
+		return "", queue.Permanent(fmt.Errorf("invalid file URL %q", src.Msg.URL))
+	}
+
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // file:///C:/videos/a.mp4 no Windows
+	}
+	// Os links simbólicos são resolvidos antes da verificação, e o caminho
+	// resolvido é o usado daqui em diante
+	path, err := filepath.EvalSymlinks(filepath.FromSlash(path))
+	if err != nil {
+		return "", queue.Permanent(fmt.Errorf("failed to open source: %w", err))
+	}
+	if !vp.config.AllowFileURLs && !vp.inFileRoots(path) {
+		return "", queue.Permanent(fmt.Errorf("%w: %s is outside the allowed directories", errURLPolicy, path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", queue.Permanent(fmt.Errorf("failed to open source: %w", err))
+	}
+	if !info.Mode().IsRegular() {
+		return "", queue.Permanent(fmt.Errorf("source %s is not a regular file", path))
+	}
+	if limit := vp.config.Download.MaxBytes; limit > 0 && info.Size() > limit {
+		return "", queue.Permanent(fmt.Errorf("source is %d bytes, larger than the maximum of %d bytes", info.Size(), limit))
+	}
+
+	logging.FromContext(ctx).Info("Using local video", "path", path, "bytes", info.Size())
+	return path, nil
+}
+
+// inFileRoots indica se o arquivo (com os links simbólicos já resolvidos) está
+// dentro de um dos diretórios de DownloadConfig.FileRoots
+func (vp *VideoProcessor) inFileRoots(path string) bool {
+	for _, root := range vp.config.Download.FileRoots {
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(resolvedRoot, path); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
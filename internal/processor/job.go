@@ -0,0 +1,28 @@
+package processor
+
+// Importações necessárias para representar um job
+import (
+	"context"                    // Para carregar o logger da etapa
+	"log/slog"                   // Para o logger do job
+	"ms-videos/internal/logging" // Para associar o logger ao contexto
+	"ms-videos/internal/queue"   // Para a mensagem que originou o job
+)
+
+// job agrupa o estado de um vídeo em processamento
+// Cada etapa preenche seus campos para que as etapas seguintes possam usá-los
+type job struct {
+	msg        queue.VideoMessage // Mensagem recebida da fila
+	tempDir    string             // Diretório temporário do job
+	sourcePath string             // Caminho local do vídeo original baixado
+	source     *queue.SourceInfo  // Hash e tamanho da fonte, calculados no download
+	media      *MediaInfo         // Metadados da fonte obtidos via ffprobe
+	profile    *Profile           // Perfil de encoding escolhido para o job
+	rungs      []Rung             // Resoluções que serão geradas para esta fonte
+	renditions []Rendition        // Variantes produzidas, com valores medidos
+	log        *slog.Logger       // Logger com o job_id, base dos loggers de cada etapa
+}
+
+// stage retorna um contexto cujo logger identifica o job e a etapa
+func (j *job) stage(ctx context.Context, stage string) context.Context {
+	return logging.WithLogger(ctx, j.log.With("stage", stage))
+}
@@ -0,0 +1,215 @@
+package processor
+
+// Importações necessárias para a inspeção de vídeos com ffprobe
+import (
+	"bytes"         // Para capturar a saída do ffprobe
+	"context"       // Para cancelar o ffprobe
+	"encoding/json" // Para decodificar a saída JSON do ffprobe
+	"fmt"           // Para formatação de strings
+	"math"          // Para arredondamento das dimensões
+	"strconv"       // Para conversão de strings numéricas
+	"strings"       // Para manipulação de strings
+	"time"          // Para representar a duração do vídeo
+)
+
+// MediaInfo contém os metadados do vídeo de origem obtidos com ffprobe
+// Esses dados ficam guardados no job para que as etapas seguintes possam usá-los
+type MediaInfo struct {
+	Width     int           // Largura do stream de vídeo em pixels
+	Height    int           // Altura do stream de vídeo em pixels
+	SAR       float64       // Sample aspect ratio (proporção do pixel; 1 = pixel quadrado)
+	FrameRate float64       // Taxa de quadros média (fps)
+	Duration  time.Duration // Duração total do arquivo
+	Rotation  int           // Rotação em graus (0, 90, 180 ou 270)
+	Streams   []StreamInfo  // Todos os streams encontrados no arquivo
+}
+
+// StreamInfo descreve um stream individual (vídeo, áudio, legenda...) do arquivo
+type StreamInfo struct {
+	Index     int    // Índice do stream no container
+	CodecType string // Tipo do stream: "video", "audio", "subtitle"...
+	CodecName string // Nome do codec (ex: "h264", "aac")
+	Profile   string // Perfil do codec (ex: "High", "LC")
+	Level     int    // Level do codec (ex: 41 para H.264 4.1)
+	Width     int    // Largura (apenas streams de vídeo)
+	Height    int    // Altura (apenas streams de vídeo)
+}
+
+// DisplaySize retorna as dimensões como o vídeo é exibido, aplicando o SAR
+// (pixels não quadrados) e a rotação informada nos metadados
+func (mi *MediaInfo) DisplaySize() (width, height int) {
+	width, height = mi.Width, mi.Height
+	if mi.SAR > 0 && mi.SAR != 1 {
+		width = int(math.Round(float64(width) * mi.SAR))
+	}
+	if mi.Rotation == 90 || mi.Rotation == 270 {
+		width, height = height, width
+	}
+	return width, height
+}
+
+// ShortEdge retorna o menor lado das dimensões de exibição
+// É a medida usada para decidir quais resoluções fazem sentido para a fonte
+func (mi *MediaInfo) ShortEdge() int {
+	width, height := mi.DisplaySize()
+	if width < height {
+		return width
+	}
+	return height
+}
+
+// HasAudio indica se o arquivo possui ao menos um stream de áudio
+func (mi *MediaInfo) HasAudio() bool {
+	for _, s := range mi.Streams {
+		if s.CodecType == "audio" {
+			return true
+		}
+	}
+	return false
+}
+
+// ffprobeOutput espelha apenas os campos da saída JSON do ffprobe que usamos
+type ffprobeOutput struct {
+	Streams []struct {
+		Index        int               `json:"index"`
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Profile      string            `json:"profile"`
+		Level        int               `json:"level"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		SampleAspect string            `json:"sample_aspect_ratio"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		RFrameRate   string            `json:"r_frame_rate"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			SideDataType string  `json:"side_data_type"`
+			Rotation     float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// probeVideo executa o ffprobe sobre o arquivo e extrai os metadados do vídeo
+func probeVideo(ctx context.Context, path string) (*MediaInfo, error) {
+	cmd := command(ctx, "ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseProbeOutput(stdout.Bytes())
+}
+
+// parseProbeOutput converte a saída JSON do ffprobe em um MediaInfo
+func parseProbeOutput(data []byte) (*MediaInfo, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{}
+	foundVideo := false
+	for _, s := range out.Streams {
+		info.Streams = append(info.Streams, StreamInfo{
+			Index:     s.Index,
+			CodecType: s.CodecType,
+			CodecName: s.CodecName,
+			Profile:   s.Profile,
+			Level:     s.Level,
+			Width:     s.Width,
+			Height:    s.Height,
+		})
+
+		// Usa apenas o primeiro stream de vídeo como referência
+		if s.CodecType != "video" || foundVideo {
+			continue
+		}
+		foundVideo = true
+
+		info.Width = s.Width
+		info.Height = s.Height
+		info.SAR = parseRatio(s.SampleAspect, ":")
+		if info.SAR <= 0 {
+			info.SAR = 1 // "N/A" ou "0:1" significam pixel quadrado
+		}
+		info.FrameRate = parseFrameRate(s.AvgFrameRate)
+		if info.FrameRate == 0 {
+			info.FrameRate = parseFrameRate(s.RFrameRate)
+		}
+
+		// A rotação pode vir da tag "rotate" (ffmpeg antigo) ou da display matrix
+		if rotate, ok := s.Tags["rotate"]; ok {
+			if r, err := strconv.Atoi(rotate); err == nil {
+				info.Rotation = normalizeRotation(r)
+			}
+		}
+		for _, sd := range s.SideDataList {
+			if sd.SideDataType == "Display Matrix" {
+				info.Rotation = normalizeRotation(int(sd.Rotation))
+			}
+		}
+	}
+
+	if !foundVideo {
+		return nil, fmt.Errorf("no video stream found")
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return nil, fmt.Errorf("invalid video dimensions %dx%d", info.Width, info.Height)
+	}
+
+	if out.Format.Duration != "" {
+		if seconds, err := strconv.ParseFloat(out.Format.Duration, 64); err == nil {
+			info.Duration = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	return info, nil
+}
+
+// parseFrameRate converte frações do ffprobe como "30000/1001" em fps
+func parseFrameRate(rate string) float64 {
+	return parseRatio(rate, "/")
+}
+
+// parseRatio converte razões como "30000/1001" ou "4:3" em um número
+// Retorna 0 quando o valor não pode ser interpretado
+func parseRatio(ratio, sep string) float64 {
+	num, den, found := strings.Cut(ratio, sep)
+	if !found {
+		value, _ := strconv.ParseFloat(ratio, 64)
+		return value
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+// normalizeRotation leva qualquer ângulo para o intervalo [0, 360)
+// A display matrix costuma reportar valores negativos como -90
+func normalizeRotation(degrees int) int {
+	degrees %= 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
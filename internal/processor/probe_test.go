@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseProbeOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		wantErr  bool
+		width    int
+		height   int
+		fps      float64
+		rotation int
+		duration time.Duration
+		hasAudio bool
+		streams  int
+	}{
+		{
+			name: "landscape with audio",
+			json: `{"streams": [
+				{"index": 0, "codec_type": "video", "codec_name": "h264", "profile": "High", "level": 41,
+				 "width": 1920, "height": 1080, "sample_aspect_ratio": "1:1",
+				 "avg_frame_rate": "30000/1001", "r_frame_rate": "30000/1001"},
+				{"index": 1, "codec_type": "audio", "codec_name": "aac", "profile": "LC"}],
+				"format": {"duration": "95.200000"}}`,
+			width: 1920, height: 1080, fps: 29.97, duration: 95200 * time.Millisecond,
+			hasAudio: true, streams: 2,
+		},
+		{
+			// A display matrix reporta -90 para vídeos de celular gravados em pé
+			name: "display matrix rotation",
+			json: `{"streams": [
+				{"index": 0, "codec_type": "video", "width": 1920, "height": 1080, "avg_frame_rate": "30/1",
+				 "side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]}],
+				"format": {"duration": "10"}}`,
+			width: 1920, height: 1080, fps: 30, rotation: 270, duration: 10 * time.Second, streams: 1,
+		},
+		{
+			name: "rotate tag",
+			json: `{"streams": [
+				{"index": 0, "codec_type": "video", "width": 1280, "height": 720, "avg_frame_rate": "25/1",
+				 "tags": {"rotate": "90"}}],
+				"format": {}}`,
+			width: 1280, height: 720, fps: 25, rotation: 90, streams: 1,
+		},
+		{
+			// avg_frame_rate "0/0" cai para r_frame_rate; duração "N/A" fica zerada
+			name: "missing average frame rate and duration",
+			json: `{"streams": [
+				{"index": 0, "codec_type": "video", "width": 640, "height": 480,
+				 "avg_frame_rate": "0/0", "r_frame_rate": "25/1"}],
+				"format": {"duration": "N/A"}}`,
+			width: 640, height: 480, fps: 25, streams: 1,
+		},
+		{
+			// Só o primeiro stream de vídeo é a referência (o segundo costuma ser a capa)
+			name: "first video stream wins",
+			json: `{"streams": [
+				{"index": 0, "codec_type": "video", "width": 1280, "height": 720, "avg_frame_rate": "24/1"},
+				{"index": 1, "codec_type": "video", "codec_name": "mjpeg", "width": 300, "height": 300}],
+				"format": {}}`,
+			width: 1280, height: 720, fps: 24, streams: 2,
+		},
+		{
+			name:    "no video stream",
+			json:    `{"streams": [{"index": 0, "codec_type": "audio", "codec_name": "mp3"}], "format": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid dimensions",
+			json:    `{"streams": [{"index": 0, "codec_type": "video", "width": 0, "height": 0}], "format": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			json:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseProbeOutput([]byte(tt.json))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if info.Width != tt.width || info.Height != tt.height {
+				t.Errorf("size = %dx%d, want %dx%d", info.Width, info.Height, tt.width, tt.height)
+			}
+			if math.Abs(info.FrameRate-tt.fps) > 0.01 {
+				t.Errorf("FrameRate = %v, want %v", info.FrameRate, tt.fps)
+			}
+			if info.Rotation != tt.rotation {
+				t.Errorf("Rotation = %d, want %d", info.Rotation, tt.rotation)
+			}
+			if info.Duration != tt.duration {
+				t.Errorf("Duration = %v, want %v", info.Duration, tt.duration)
+			}
+			if info.HasAudio() != tt.hasAudio {
+				t.Errorf("HasAudio = %v, want %v", info.HasAudio(), tt.hasAudio)
+			}
+			if len(info.Streams) != tt.streams {
+				t.Errorf("got %d streams, want %d", len(info.Streams), tt.streams)
+			}
+		})
+	}
+}
+
+func TestParseProbeOutputStreams(t *testing.T) {
+	info, err := parseProbeOutput([]byte(`{"streams": [
+		{"index": 0, "codec_type": "video", "codec_name": "h264", "profile": "Main", "level": 31, "width": 1280, "height": 720},
+		{"index": 1, "codec_type": "audio", "codec_name": "aac", "profile": "HE-AAC"}],
+		"format": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []StreamInfo{
+		{Index: 0, CodecType: "video", CodecName: "h264", Profile: "Main", Level: 31, Width: 1280, Height: 720},
+		{Index: 1, CodecType: "audio", CodecName: "aac", Profile: "HE-AAC"},
+	}
+	for i, s := range want {
+		if info.Streams[i] != s {
+			t.Errorf("stream %d = %+v, want %+v", i, info.Streams[i], s)
+		}
+	}
+}
+
+func TestNormalizeRotation(t *testing.T) {
+	tests := map[int]int{0: 0, 90: 90, -90: 270, 180: 180, -180: 180, 270: 270, 360: 0, 450: 90, -450: 270}
+	for in, want := range tests {
+		if got := normalizeRotation(in); got != want {
+			t.Errorf("normalizeRotation(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseProbeOutputGeometry(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		sar       float64
+		displayW  int
+		displayH  int
+		shortEdge int
+	}{
+		{
+			name: "square pixels",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 1920, "height": 1080,
+				"sample_aspect_ratio": "1:1"}], "format": {}}`,
+			sar: 1, displayW: 1920, displayH: 1080, shortEdge: 1080,
+		},
+		{
+			// SAR "N/A" ou "0:1" vira pixel quadrado
+			name: "missing sar",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 640, "height": 480,
+				"sample_aspect_ratio": "N/A"}], "format": {}}`,
+			sar: 1, displayW: 640, displayH: 480, shortEdge: 480,
+		},
+		{
+			name: "zero sar",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 640, "height": 480,
+				"sample_aspect_ratio": "0:1"}], "format": {}}`,
+			sar: 1, displayW: 640, displayH: 480, shortEdge: 480,
+		},
+		{
+			// DVD anamórfico: pixels largos, exibido em 16:9
+			name: "anamorphic",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 720, "height": 576,
+				"sample_aspect_ratio": "64:45"}], "format": {}}`,
+			sar: 64.0 / 45, displayW: 1024, displayH: 576, shortEdge: 576,
+		},
+		{
+			// Gravado em pé: as dimensões de exibição são trocadas
+			name: "display matrix rotation",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 1920, "height": 1080,
+				"side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]}], "format": {}}`,
+			sar: 1, displayW: 1080, displayH: 1920, shortEdge: 1080,
+		},
+		{
+			// O SAR vale para a largura armazenada, antes da rotação
+			name: "anamorphic and rotated",
+			json: `{"streams": [{"index": 0, "codec_type": "video", "width": 1440, "height": 1080,
+				"sample_aspect_ratio": "4:3", "tags": {"rotate": "90"}}], "format": {}}`,
+			sar: 4.0 / 3, displayW: 1080, displayH: 1920, shortEdge: 1080,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseProbeOutput([]byte(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(info.SAR-tt.sar) > 1e-9 {
+				t.Errorf("SAR = %v, want %v", info.SAR, tt.sar)
+			}
+			if w, h := info.DisplaySize(); w != tt.displayW || h != tt.displayH {
+				t.Errorf("DisplaySize = %dx%d, want %dx%d", w, h, tt.displayW, tt.displayH)
+			}
+			if got := info.ShortEdge(); got != tt.shortEdge {
+				t.Errorf("ShortEdge = %d, want %d", got, tt.shortEdge)
+			}
+		})
+	}
+}
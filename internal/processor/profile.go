@@ -0,0 +1,184 @@
+package processor
+
+// Importações necessárias para carregar os perfis de encoding
+import (
+	"encoding/json" // Para decodificar o arquivo de configuração
+	"fmt"           // Para formatação de strings
+	"os"            // Para leitura do arquivo de configuração
+	"regexp"        // Para validar nomes de perfis e resoluções
+	"sort"          // Para ordenar as resoluções da maior para a menor
+)
+
+// Rung descreve um degrau da escada de resoluções (uma variante HLS)
+type Rung struct {
+	Name         string `json:"name"`          // Nome da variante, usado como diretório (ex: "720p")
+	Width        int    `json:"width"`         // Largura da caixa em pixels (modo bounding_box)
+	Height       int    `json:"height"`        // Lado menor alvo em pixels (ou altura da caixa, no modo bounding_box)
+	VideoBitrate int    `json:"video_bitrate"` // Bitrate alvo do vídeo em bits/s
+	MaxRate      int    `json:"maxrate"`       // Bitrate máximo do vídeo em bits/s
+	BufSize      int    `json:"bufsize"`       // Tamanho do buffer VBV em bits
+	Profile      string `json:"profile"`       // Perfil H.264 (baseline, main, high)
+	Level        string `json:"level"`         // Level H.264 (ex: "4.2"); vazio deixa o x264 escolher pela resolução, frame rate e VBV
+}
+
+// Modos de enquadramento das resoluções de um perfil
+const (
+	// FitShortEdge aplica a altura do degrau ao menor lado do vídeo, seja ele
+	// paisagem ou retrato (um vídeo 1080x1920 gera um "720p" de 720x1280)
+	FitShortEdge = "short_edge"
+	// FitBoundingBox encaixa o vídeo na caixa width x height do degrau,
+	// girando a caixa para acompanhar a orientação da fonte
+	FitBoundingBox = "bounding_box"
+)
+
+// Profile é uma escada de encoding nomeada
+type Profile struct {
+	Name         string `json:"-"`             // Nome do perfil (chave no arquivo de configuração)
+	Fit          string `json:"fit"`           // Modo de enquadramento (short_edge ou bounding_box)
+	Preset       string `json:"preset"`        // Preset do libx264 (ex: "veryfast")
+	AudioBitrate int    `json:"audio_bitrate"` // Bitrate do áudio AAC em bits/s
+	Rungs        []Rung `json:"rungs"`         // Resoluções, da maior para a menor
+}
+
+// ProfileSet agrupa todos os perfis disponíveis e qual deles é o padrão
+type ProfileSet struct {
+	Default  string              `json:"default"`  // Perfil usado quando a mensagem não especifica um
+	Profiles map[string]*Profile `json:"profiles"` // Perfis indexados pelo nome
+}
+
+// validName restringe nomes que viram caminhos de diretório e chaves de objeto
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DefaultProfiles retorna a escada embutida usada quando nenhum arquivo é configurado
+// Os levels ficam a cargo do x264: um level fixo erraria para fontes de 50/60fps
+func DefaultProfiles() *ProfileSet {
+	set := &ProfileSet{
+		Default: "standard",
+		Profiles: map[string]*Profile{
+			"standard": {
+				Fit:          FitShortEdge,
+				Preset:       "veryfast",
+				AudioBitrate: 128000,
+				Rungs: []Rung{
+					{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: 5000000, MaxRate: 5350000, BufSize: 7500000, Profile: "high"},
+					{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 3000000, MaxRate: 3210000, BufSize: 4500000, Profile: "high"},
+					{Name: "480p", Width: 854, Height: 480, VideoBitrate: 1500000, MaxRate: 1605000, BufSize: 2250000, Profile: "main"},
+					{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800000, MaxRate: 856000, BufSize: 1200000, Profile: "main"},
+				},
+			},
+		},
+	}
+	// A escada embutida é válida por construção; validate só preenche os nomes
+	if err := set.validate(); err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// LoadProfiles lê os perfis de um arquivo JSON
+// Se path estiver vazio, retorna os perfis embutidos
+func LoadProfiles(path string) (*ProfileSet, error) {
+	if path == "" {
+		return DefaultProfiles(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	var set ProfileSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles file %s: %w", path, err)
+	}
+
+	if err := set.validate(); err != nil {
+		return nil, fmt.Errorf("invalid profiles file %s: %w", path, err)
+	}
+	return &set, nil
+}
+
+// Get retorna o perfil pelo nome, ou o perfil padrão se o nome estiver vazio
+func (ps *ProfileSet) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = ps.Default
+	}
+	profile, ok := ps.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding profile: %s", name)
+	}
+	return profile, nil
+}
+
+// validate confere a consistência dos perfis e ordena as resoluções
+func (ps *ProfileSet) validate() error {
+	if len(ps.Profiles) == 0 {
+		return fmt.Errorf("no profiles defined")
+	}
+	if _, ok := ps.Profiles[ps.Default]; !ok {
+		return fmt.Errorf("default profile %q is not defined", ps.Default)
+	}
+
+	for name, profile := range ps.Profiles {
+		if profile == nil {
+			return fmt.Errorf("profile %q is empty", name)
+		}
+		if !validName.MatchString(name) {
+			return fmt.Errorf("invalid profile name %q", name)
+		}
+		profile.Name = name
+
+		switch profile.Fit {
+		case "":
+			profile.Fit = FitShortEdge
+		case FitShortEdge, FitBoundingBox:
+		default:
+			return fmt.Errorf("profile %q: unknown fit mode %q", name, profile.Fit)
+		}
+		if profile.Preset == "" {
+			profile.Preset = "veryfast"
+		}
+		if profile.AudioBitrate <= 0 {
+			return fmt.Errorf("profile %q: audio_bitrate must be positive", name)
+		}
+		if len(profile.Rungs) == 0 {
+			return fmt.Errorf("profile %q has no rungs", name)
+		}
+
+		seen := make(map[string]bool)
+		for i := range profile.Rungs {
+			rung := &profile.Rungs[i]
+			if !validName.MatchString(rung.Name) {
+				return fmt.Errorf("profile %q: invalid rung name %q", name, rung.Name)
+			}
+			if seen[rung.Name] {
+				return fmt.Errorf("profile %q: duplicate rung %q", name, rung.Name)
+			}
+			seen[rung.Name] = true
+
+			if rung.Height <= 0 || rung.Width <= 0 {
+				return fmt.Errorf("profile %q: rung %q must have positive width and height", name, rung.Name)
+			}
+			if rung.VideoBitrate <= 0 {
+				return fmt.Errorf("profile %q: rung %q must have a positive video_bitrate", name, rung.Name)
+			}
+			// maxrate e bufsize seguem as proporções usuais quando omitidos
+			if rung.MaxRate == 0 {
+				rung.MaxRate = rung.VideoBitrate * 107 / 100
+			}
+			if rung.BufSize == 0 {
+				rung.BufSize = rung.VideoBitrate * 3 / 2
+			}
+			if rung.Profile == "" {
+				rung.Profile = "main"
+			}
+		}
+
+		// Mantém a escada sempre da maior para a menor resolução
+		sort.SliceStable(profile.Rungs, func(i, j int) bool {
+			return profile.Rungs[i].Height > profile.Rungs[j].Height
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"slices"
+	"testing"
+)
+
+// Os perfis embutidos deixam o level para o x264, que o escolhe pelo frame rate
+// da fonte: um level fixo seria pequeno demais para fontes de 60fps
+func TestDefaultProfilesLeaveLevelToEncoder(t *testing.T) {
+	profile, err := DefaultProfiles().Get("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rung := range profile.Rungs {
+		if rung.Level != "" {
+			t.Errorf("%s: built-in level %s", rung.Name, rung.Level)
+		}
+		if args := videoCodecArgs(profile, rung, ":v"); slices.Contains(args, "-level:v") {
+			t.Errorf("%s: ffmpeg arguments set a level: %v", rung.Name, args)
+		}
+	}
+
+	// Um level configurado explicitamente continua sendo repassado
+	rung := profile.Rungs[0]
+	rung.Level = "4.2"
+	args := videoCodecArgs(profile, rung, ":v:0")
+	if i := slices.Index(args, "-level:v:0"); i < 0 || args[i+1] != "4.2" {
+		t.Errorf("explicit level not passed to ffmpeg: %v", args)
+	}
+}
+
+func TestLoadProfilesExample(t *testing.T) {
+	set, err := LoadProfiles("../../examples/ladder.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"standard", "premium"} {
+		if _, err := set.Get(name); err != nil {
+			t.Errorf("example profile %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package processor
+
+// Importações necessárias para acompanhar o progresso do ffmpeg
+import (
+	"bufio"                      // Para leitura linha a linha da saída de progresso
+	"context"                    // Para o logger da variante
+	"io"                         // Para o leitor da saída de progresso
+	"math"                       // Para agrupar o progresso em degraus
+	"ms-videos/internal/logging" // Para o logger da variante
+	"ms-videos/internal/queue"   // Para os eventos de progresso
+	"strconv"                    // Para conversão dos valores numéricos
+	"strings"                    // Para manipulação de strings
+	"sync"                       // Para proteger o estado compartilhado entre goroutines
+	"time"                       // Para representar o tempo já codificado
+)
+
+// progressStep é o intervalo, em pontos percentuais, entre logs e eventos de progresso
+// O progresso consultado via Progress é sempre o mais recente
+const progressStep = 10
+
+// Progress é o progresso de uma execução do ffmpeg, lido de -progress
+type Progress struct {
+	OutTime time.Duration // Quanto da fonte já foi codificado
+	Speed   float64       // Velocidade em relação ao tempo real (ex: 1.5 = 1.5x)
+	FPS     float64       // Quadros codificados por segundo
+	Percent float64       // OutTime em relação à duração da fonte, de 0 a 100
+	Done    bool          // O ffmpeg terminou de escrever a saída
+}
+
+// parseProgress lê os blocos "chave=valor" que o ffmpeg escreve com -progress
+// e chama onUpdate ao fim de cada bloco (linha "progress=continue" ou "progress=end")
+// duration é a duração da fonte, usada para calcular o percentual
+func parseProgress(r io.Reader, duration time.Duration, onUpdate func(Progress)) error {
+	var p Progress
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "out_time_us", "out_time_ms":
+			// Apesar do nome, out_time_ms também é em microssegundos
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				p.OutTime = time.Duration(us) * time.Microsecond
+			}
+		case "speed":
+			// Formato "1.23x", ou "N/A" no início do encode
+			if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+				p.Speed = speed
+			}
+		case "fps":
+			if fps, err := strconv.ParseFloat(value, 64); err == nil {
+				p.FPS = fps
+			}
+		case "progress":
+			p.Done = value == "end"
+			p.Percent = progressPercent(p.OutTime, duration)
+			if p.Done {
+				p.Percent = 100
+			}
+			onUpdate(p)
+		}
+	}
+	return scanner.Err()
+}
+
+// progressPercent calcula o percentual codificado, limitado a [0, 100)
+// O 100 fica reservado para o fim real do ffmpeg
+func progressPercent(outTime, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	percent := float64(outTime) / float64(duration) * 100
+	if percent >= 100 {
+		return 99.9
+	}
+	return percent
+}
+
+// Progress retorna o progresso mais recente de cada variante de um job em encoding
+// O segundo retorno é false se o job não estiver sendo codificado
+func (vp *VideoProcessor) Progress(jobID string) (map[string]Progress, bool) {
+	return vp.progress.get(jobID)
+}
+
+// progressReporter cria o callback de progresso de uma execução do ffmpeg que
+// produz as variantes informadas: registra o progresso de cada uma e, a cada
+// progressStep pontos percentuais, escreve no log e publica eventos de encoding
+func (vp *VideoProcessor) progressReporter(ctx context.Context, j *job, renditions ...string) func(Progress) {
+	logger := logging.FromContext(ctx)
+	lastStep := -1.0
+	return func(p Progress) {
+		for _, name := range renditions {
+			vp.progress.update(j.msg.ID, name, p)
+		}
+
+		step := math.Floor(p.Percent / progressStep)
+		if step == lastStep && !p.Done {
+			return
+		}
+		lastStep = step
+
+		logger.Info("Encoding progress", "percent", math.Round(p.Percent*10)/10,
+			"out_time", p.OutTime.Truncate(time.Second).String(), "speed", p.Speed, "fps", p.FPS)
+		for _, name := range renditions {
+			vp.emit(queue.JobEvent{
+				Type:           queue.EventEncoding,
+				JobID:          j.msg.ID,
+				Rendition:      name,
+				Percent:        math.Round(p.Percent*10) / 10,
+				OutTimeSeconds: p.OutTime.Seconds(),
+				Speed:          p.Speed,
+				FPS:            p.FPS,
+			})
+		}
+	}
+}
+
+// progressTracker guarda o progresso mais recente de cada variante dos jobs em andamento
+type progressTracker struct {
+	mu   sync.Mutex
+	jobs map[string]map[string]Progress // ID do vídeo -> variante -> progresso
+}
+
+// newProgressTracker cria um progressTracker vazio
+func newProgressTracker() *progressTracker {
+	return &progressTracker{jobs: make(map[string]map[string]Progress)}
+}
+
+// update registra o progresso de uma variante
+func (pt *progressTracker) update(jobID, rendition string, p Progress) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	renditions, ok := pt.jobs[jobID]
+	if !ok {
+		renditions = make(map[string]Progress)
+		pt.jobs[jobID] = renditions
+	}
+	renditions[rendition] = p
+}
+
+// get retorna uma cópia do progresso das variantes de um job
+func (pt *progressTracker) get(jobID string) (map[string]Progress, bool) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	renditions, ok := pt.jobs[jobID]
+	if !ok {
+		return nil, false
+	}
+	out := make(map[string]Progress, len(renditions))
+	for name, p := range renditions {
+		out[name] = p
+	}
+	return out, true
+}
+
+// remove descarta o progresso de um job finalizado
+func (pt *progressTracker) remove(jobID string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	delete(pt.jobs, jobID)
+}
+
+// tailBuffer guarda apenas os últimos bytes escritos
+// É usado para anexar o final do stderr do ffmpeg aos erros sem guardar o log inteiro
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+// newTailBuffer cria um tailBuffer que mantém até max bytes
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write implementa io.Writer descartando o início quando o limite é excedido
+func (tb *tailBuffer) Write(p []byte) (int, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.data = append(tb.data, p...)
+	if over := len(tb.data) - tb.max; over > 0 {
+		tb.data = append(tb.data[:0], tb.data[over:]...)
+	}
+	return len(p), nil
+}
+
+// String retorna o conteúdo guardado, começando na primeira linha completa
+func (tb *tailBuffer) String() string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	text := string(tb.data)
+	if len(tb.data) == tb.max {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	return strings.TrimSpace(text)
+}
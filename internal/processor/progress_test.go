@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		duration time.Duration
+		want     []Progress
+	}{
+		{
+			name: "blocks until end",
+			input: `frame=120
+fps=30.00
+out_time_us=4000000
+out_time=00:00:04.000000
+speed=1.50x
+progress=continue
+frame=300
+fps=29.5
+out_time_us=10000000
+speed=1.48x
+progress=end
+`,
+			duration: 10 * time.Second,
+			want: []Progress{
+				{OutTime: 4 * time.Second, Speed: 1.5, FPS: 30, Percent: 40},
+				// O fim é sempre 100%
+				{OutTime: 10 * time.Second, Speed: 1.48, FPS: 29.5, Percent: 100, Done: true},
+			},
+		},
+		{
+			// out_time_ms também é em microssegundos; speed "N/A" mantém o valor anterior
+			name: "out_time_ms and N/A speed",
+			input: `out_time_ms=2500000
+speed=N/A
+progress=continue
+`,
+			duration: 10 * time.Second,
+			want:     []Progress{{OutTime: 2500 * time.Millisecond, Percent: 25}},
+		},
+		{
+			// Antes do fim, o percentual nunca chega a 100
+			name: "past the probed duration",
+			input: `out_time_us=12000000
+progress=continue
+`,
+			duration: 10 * time.Second,
+			want:     []Progress{{OutTime: 12 * time.Second, Percent: 99.9}},
+		},
+		{
+			// Valores negativos (início do encode) e linhas sem "=" são ignorados
+			name: "negative out time and noise",
+			input: `out_time_us=-9223372036854775807
+garbage line
+  fps=12.5
+progress=continue
+`,
+			duration: 10 * time.Second,
+			want:     []Progress{{FPS: 12.5}},
+		},
+		{
+			name: "unknown duration",
+			input: `out_time_us=3000000
+progress=continue
+`,
+			want: []Progress{{OutTime: 3 * time.Second}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Progress
+			err := parseProgress(strings.NewReader(tt.input), tt.duration, func(p Progress) {
+				got = append(got, p)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d updates, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("update %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := newTailBuffer(16)
+	tb.Write([]byte("first line\nsecond line\n"))
+	tb.Write([]byte("third\n"))
+
+	// O início cortado é descartado até a primeira linha completa
+	if got := tb.String(); got != "third" {
+		t.Errorf("String() = %q, want %q", got, "third")
+	}
+}
@@ -0,0 +1,186 @@
+package processor
+
+// Importações necessárias para medir as variantes geradas
+import (
+	"bufio"         // Para leitura linha a linha das playlists
+	"context"       // Para cancelar o ffprobe
+	"fmt"           // Para formatação de strings
+	"math"          // Para arredondamento dos bitrates
+	"os"            // Para abrir arquivos e obter tamanhos
+	"path/filepath" // Para manipulação de caminhos de arquivos
+	"strconv"       // Para conversão de números
+	"strings"       // Para manipulação de strings
+)
+
+// Rendition descreve uma variante HLS já produzida, com dados medidos dos arquivos gerados
+// É a partir dela que a playlist mestre é escrita
+type Rendition struct {
+	Name             string  // Nome da variante (diretório)
+	Width            int     // Largura real do vídeo codificado
+	Height           int     // Altura real do vídeo codificado
+	FrameRate        float64 // Taxa de quadros do vídeo codificado
+	PeakBandwidth    int     // Maior bitrate de segmento em bits/s
+	AverageBandwidth int     // Bitrate médio em bits/s
+	Codecs           string  // Codecs no formato RFC 6381 (ex: "avc1.64001f,mp4a.40.2")
+	Playlist         string  // Caminho da playlist relativo ao diretório hls
+}
+
+// mediaSegment é uma entrada da playlist de mídia
+type mediaSegment struct {
+	uri      string  // Caminho do segmento relativo à playlist
+	duration float64 // Duração em segundos (#EXTINF)
+}
+
+// measureRendition lê a playlist e os segmentos de uma variante para obter
+// bitrates de pico e médio, dimensões reais, frame rate e codecs
+func measureRendition(ctx context.Context, hlsDir, name string) (*Rendition, error) {
+	playlist := filepath.Join(hlsDir, name, "playlist.m3u8")
+	segments, err := parseMediaPlaylist(playlist)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("playlist %s has no segments", playlist)
+	}
+
+	// O pico é o maior bitrate de um segmento; a média considera a variante inteira
+	var totalBits, totalDuration, peak float64
+	for _, seg := range segments {
+		info, err := os.Stat(filepath.Join(filepath.Dir(playlist), seg.uri))
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat segment %s: %w", seg.uri, err)
+		}
+
+		bits := float64(info.Size()) * 8
+		totalBits += bits
+		totalDuration += seg.duration
+		if seg.duration > 0 {
+			peak = math.Max(peak, bits/seg.duration)
+		}
+	}
+	if totalDuration <= 0 {
+		return nil, fmt.Errorf("playlist %s has zero duration", playlist)
+	}
+
+	// Inspeciona o primeiro segmento para descobrir o que foi realmente codificado
+	media, err := probeVideo(ctx, filepath.Join(filepath.Dir(playlist), segments[0].uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe %s segment: %w", name, err)
+	}
+
+	return &Rendition{
+		Name:             name,
+		Width:            media.Width,
+		Height:           media.Height,
+		FrameRate:        media.FrameRate,
+		PeakBandwidth:    int(math.Ceil(peak)),
+		AverageBandwidth: int(math.Ceil(totalBits / totalDuration)),
+		Codecs:           codecsString(media.Streams),
+		Playlist:         name + "/playlist.m3u8",
+	}, nil
+}
+
+// parseMediaPlaylist extrai os segmentos e suas durações de uma playlist de mídia HLS
+func parseMediaPlaylist(path string) ([]mediaSegment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open playlist: %w", err)
+	}
+	defer file.Close()
+
+	var segments []mediaSegment
+	var duration float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// Formato: #EXTINF:<duração>,[título]
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			duration, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EXTINF in %s: %q", path, line)
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		default:
+			segments = append(segments, mediaSegment{uri: line, duration: duration})
+			duration = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	return segments, nil
+}
+
+// codecsString monta o atributo CODECS (RFC 6381) a partir dos streams de um segmento
+// Streams com codec desconhecido são omitidos
+func codecsString(streams []StreamInfo) string {
+	var codecs []string
+	for _, s := range streams {
+		var codec string
+		switch s.CodecName {
+		case "h264":
+			codec = avcCodecString(s.Profile, s.Level)
+		case "aac":
+			codec = aacCodecString(s.Profile)
+		case "mp3":
+			codec = "mp4a.40.34"
+		case "ac3":
+			codec = "ac-3"
+		case "eac3":
+			codec = "ec-3"
+		}
+		if codec != "" {
+			codecs = append(codecs, codec)
+		}
+	}
+	return strings.Join(codecs, ",")
+}
+
+// avcCodecString gera "avc1.PPCCLL" (ex: "avc1.64001f"): profile_idc, flags de
+// restrição e level_idc em hexadecimal minúsculo
+// O ffprobe não informa os flags constraint_set, então eles são assumidos a partir
+// do perfil (Constrained Baseline = 0xe0, Main = 0x40, demais = 0x00) e podem
+// diferir dos gravados no SPS pelo encoder
+func avcCodecString(profile string, level int) string {
+	var profileIDC, constraints int
+	switch profile {
+	case "Constrained Baseline":
+		profileIDC, constraints = 66, 0xE0
+	case "Baseline":
+		profileIDC, constraints = 66, 0x00
+	case "Main":
+		profileIDC, constraints = 77, 0x40
+	case "Extended":
+		profileIDC, constraints = 88, 0x00
+	case "High":
+		profileIDC, constraints = 100, 0x00
+	case "High 10":
+		profileIDC, constraints = 110, 0x00
+	case "High 4:2:2":
+		profileIDC, constraints = 122, 0x00
+	case "High 4:4:4 Predictive":
+		profileIDC, constraints = 244, 0x00
+	default:
+		return ""
+	}
+	if level <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("avc1.%02x%02x%02x", profileIDC, constraints, level)
+}
+
+// aacCodecString gera "mp4a.40.<object type>" de acordo com o perfil AAC
+func aacCodecString(profile string) string {
+	switch profile {
+	case "HE-AAC":
+		return "mp4a.40.5"
+	case "HE-AACv2":
+		return "mp4a.40.29"
+	default:
+		return "mp4a.40.2" // AAC-LC
+	}
+}
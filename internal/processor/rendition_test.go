@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestCodecsString(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams []StreamInfo
+		want    string
+	}{
+		{
+			name: "high with aac lc",
+			streams: []StreamInfo{
+				{CodecType: "video", CodecName: "h264", Profile: "High", Level: 31},
+				{CodecType: "audio", CodecName: "aac", Profile: "LC"},
+			},
+			want: "avc1.64001f,mp4a.40.2",
+		},
+		{
+			name:    "main level 4.1",
+			streams: []StreamInfo{{CodecType: "video", CodecName: "h264", Profile: "Main", Level: 41}},
+			want:    "avc1.4d4029",
+		},
+		{
+			name:    "constrained baseline",
+			streams: []StreamInfo{{CodecType: "video", CodecName: "h264", Profile: "Constrained Baseline", Level: 30}},
+			want:    "avc1.42e01e",
+		},
+		{
+			name: "he-aac and unknown codecs",
+			streams: []StreamInfo{
+				{CodecType: "video", CodecName: "h264", Profile: "High", Level: 40},
+				{CodecType: "audio", CodecName: "aac", Profile: "HE-AAC"},
+				{CodecType: "data", CodecName: "timed_id3"},
+			},
+			want: "avc1.640028,mp4a.40.5",
+		},
+		{
+			// Sem level o codec de vídeo não pode ser descrito e é omitido
+			name:    "missing level",
+			streams: []StreamInfo{{CodecType: "video", CodecName: "h264", Profile: "High"}, {CodecType: "audio", CodecName: "mp3"}},
+			want:    "mp4a.40.34",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codecsString(tt.streams); got != tt.want {
+				t.Errorf("codecsString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package processor
+
+// Importações necessárias para o cálculo das dimensões de saída
+import (
+	"math" // Para arredondamento e comparação de escalas
+)
+
+// rungScale retorna o fator de escala que leva as dimensões de exibição da fonte
+// até o degrau, de acordo com o modo de enquadramento do perfil
+// Valores acima de 1 significam upscale
+func rungScale(media *MediaInfo, profile *Profile, rung Rung) float64 {
+	width, height := media.DisplaySize()
+
+	if profile.Fit == FitBoundingBox {
+		// A caixa acompanha a orientação da fonte (retrato usa a caixa girada)
+		boxWidth, boxHeight := rung.Width, rung.Height
+		if height > width {
+			boxWidth, boxHeight = boxHeight, boxWidth
+		}
+		return math.Min(float64(boxWidth)/float64(width), float64(boxHeight)/float64(height))
+	}
+
+	return float64(rung.Height) / float64(media.ShortEdge())
+}
+
+// outputSize calcula largura e altura de saída de um degrau para a fonte,
+// preservando a proporção de exibição (já considerando SAR e rotação)
+func outputSize(media *MediaInfo, profile *Profile, rung Rung) (width, height int) {
+	displayWidth, displayHeight := media.DisplaySize()
+	scale := rungScale(media, profile, rung)
+	return evenDimension(float64(displayWidth) * scale), evenDimension(float64(displayHeight) * scale)
+}
+
+// evenDimension arredonda para o par mais próximo, exigido pelo libx264 com yuv420p
+func evenDimension(value float64) int {
+	n := int(math.Round(value/2)) * 2
+	if n < 2 {
+		return 2
+	}
+	return n
+}
+
+// selectRungs descarta as resoluções que fariam upscale da fonte
+// Se a fonte for menor que todas, mantém apenas a menor resolução da escada
+func selectRungs(profile *Profile, media *MediaInfo) []Rung {
+	var selected []Rung
+	for _, rung := range profile.Rungs {
+		// Pequena tolerância para fontes com dimensões ímpares (ex: 853x480)
+		if rungScale(media, profile, rung) <= 1.001 {
+			selected = append(selected, rung)
+		}
+	}
+
+	// A escada está ordenada da maior para a menor resolução
+	if len(selected) == 0 && len(profile.Rungs) > 0 {
+		selected = []Rung{profile.Rungs[len(profile.Rungs)-1]}
+	}
+	return selected
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// boundingBoxProfile encaixa o vídeo em caixas 16:9, giradas para fontes em retrato
+var boundingBoxProfile = &Profile{
+	Fit: FitBoundingBox,
+	Rungs: []Rung{
+		{Name: "720p", Width: 1280, Height: 720},
+		{Name: "360p", Width: 640, Height: 360},
+	},
+}
+
+func TestSelectRungsAndOutputSize(t *testing.T) {
+	standard, err := DefaultProfiles().Get("standard")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		profile *Profile
+		media   MediaInfo
+		want    string // "<variante>=<largura>x<altura>" de cada degrau selecionado
+	}{
+		{
+			name:    "landscape 1080p",
+			profile: standard,
+			media:   MediaInfo{Width: 1920, Height: 1080, SAR: 1},
+			want:    "1080p=1920x1080 720p=1280x720 480p=854x480 360p=640x360",
+		},
+		{
+			// O lado menor é a largura: o "720p" de um vídeo em pé tem 720 de largura
+			name:    "portrait",
+			profile: standard,
+			media:   MediaInfo{Width: 1080, Height: 1920, SAR: 1},
+			want:    "1080p=1080x1920 720p=720x1280 480p=480x854 360p=360x640",
+		},
+		{
+			// Gravado em paisagem com rotação de 90°: exibido em pé
+			name:    "rotated 90",
+			profile: standard,
+			media:   MediaInfo{Width: 1280, Height: 720, SAR: 1, Rotation: 90},
+			want:    "720p=720x1280 480p=480x854 360p=360x640",
+		},
+		{
+			// Rotação de 180° não troca as dimensões
+			name:    "rotated 180",
+			profile: standard,
+			media:   MediaInfo{Width: 1280, Height: 720, SAR: 1, Rotation: 180},
+			want:    "720p=1280x720 480p=854x480 360p=640x360",
+		},
+		{
+			// 1440x1080 com pixels 4:3 é exibido como 1920x1080
+			name:    "anamorphic sar",
+			profile: standard,
+			media:   MediaInfo{Width: 1440, Height: 1080, SAR: 4.0 / 3},
+			want:    "1080p=1920x1080 720p=1280x720 480p=854x480 360p=640x360",
+		},
+		{
+			// SAR e rotação juntos: o SAR vale para a largura armazenada
+			name:    "anamorphic and rotated",
+			profile: standard,
+			media:   MediaInfo{Width: 1440, Height: 1080, SAR: 4.0 / 3, Rotation: 270},
+			want:    "1080p=1080x1920 720p=720x1280 480p=480x854 360p=360x640",
+		},
+		{
+			// Dimensões ímpares dentro da tolerância não descartam o degrau
+			name:    "odd 480p source",
+			profile: standard,
+			media:   MediaInfo{Width: 853, Height: 480, SAR: 1},
+			want:    "480p=854x480 360p=640x360",
+		},
+		{
+			// Fonte menor que todos os degraus: só o menor, mesmo com upscale
+			name:    "tiny source keeps smallest rung",
+			profile: standard,
+			media:   MediaInfo{Width: 320, Height: 180, SAR: 1},
+			want:    "360p=640x360",
+		},
+		{
+			name:    "bounding box landscape wider than 16:9",
+			profile: boundingBoxProfile,
+			media:   MediaInfo{Width: 2560, Height: 1080, SAR: 1},
+			want:    "720p=1280x540 360p=640x270",
+		},
+		{
+			// A caixa gira para 720x1280 e o vídeo em pé é encaixado nela
+			name:    "bounding box portrait",
+			profile: boundingBoxProfile,
+			media:   MediaInfo{Width: 1080, Height: 1920, SAR: 1},
+			want:    "720p=720x1280 360p=360x640",
+		},
+		{
+			name:    "bounding box square",
+			profile: boundingBoxProfile,
+			media:   MediaInfo{Width: 1000, Height: 1000, SAR: 1},
+			want:    "720p=720x720 360p=360x360",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, rung := range selectRungs(tt.profile, &tt.media) {
+				width, height := outputSize(&tt.media, tt.profile, rung)
+				if width%2 != 0 || height%2 != 0 {
+					t.Errorf("%s: odd output size %dx%d", rung.Name, width, height)
+				}
+				got = append(got, fmt.Sprintf("%s=%dx%d", rung.Name, width, height))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got  %s\nwant %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvenDimension(t *testing.T) {
+	tests := map[float64]int{0: 2, 1: 2, 2: 2, 853: 854, 853.3: 854, 719: 720, 720.9: 720, 1079.5: 1080}
+	for in, want := range tests {
+		if got := evenDimension(in); got != want {
+			t.Errorf("evenDimension(%v) = %d, want %d", in, got, want)
+		}
+	}
+}
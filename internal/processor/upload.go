@@ -0,0 +1,222 @@
+package processor
+
+// Importações necessárias para o upload dos arquivos HLS
+import (
+	"context"                    // Para cancelar os uploads irmãos quando um falha
+	"errors"                     // Para identificar objetos inexistentes
+	"fmt"                        // Para formatação de strings
+	"io/fs"                      // Para percorrer o diretório HLS
+	"ms-videos/internal/logging" // Para o logger do job
+	"ms-videos/internal/metrics" // Para as métricas de bytes enviados
+	"ms-videos/internal/storage" // Para o armazenamento dos arquivos HLS
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"strings"                    // Para manipulação de strings
+	"sync"                       // Para coordenar os uploads paralelos
+	"sync/atomic"                // Para contar objetos enviados e reaproveitados
+	"time"                       // Para o atraso entre tentativas
+)
+
+// Padrões dos uploads (veja Config)
+const (
+	defaultUploadConcurrency = 8                // Uploads simultâneos
+	defaultUploadRetryDelay  = time.Second      // Atraso antes da 1ª nova tentativa
+	maxUploadRetryDelay      = 30 * time.Second // Atraso máximo entre tentativas
+)
+
+// uploadFile é um arquivo HLS a ser enviado
+type uploadFile struct {
+	path        string // Caminho local
+	key         string // Chave do objeto no armazenamento
+	contentType string // Tipo MIME
+	size        int64  // Tamanho em bytes
+}
+
+// uploadHLSFiles envia os arquivos HLS do job com até UploadConcurrency uploads simultâneos
+// Os segmentos vão primeiro, depois as playlists das variantes e por último a
+// playlist mestre, para que um player nunca encontre uma playlist apontando para
+// segmentos ainda não enviados
+// Objetos que já existem com o mesmo tamanho e ETag (de uma execução anterior
+// do mesmo job) não são enviados de novo
+func (vp *VideoProcessor) uploadHLSFiles(ctx context.Context, tempDir, videoID string) error {
+	logger := logging.FromContext(ctx)
+	hlsDir := filepath.Join(tempDir, "hls")
+
+	var segments, playlists, master []uploadFile
+	err := filepath.WalkDir(hlsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		// Only upload .m3u8 and .ts files
+		ext := filepath.Ext(path)
+		if ext != ".m3u8" && ext != ".ts" {
+			return nil
+		}
+
+		// Generate object key (relative path from hls directory)
+		relPath, err := filepath.Rel(hlsDir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// Convert Windows paths to Unix-style for object keys
+		relPath = strings.ReplaceAll(relPath, "\\", "/")
+		file := uploadFile{
+			path: path,
+			key:  fmt.Sprintf("%s/%s", videoID, relPath),
+			size: info.Size(),
+		}
+
+		// Set content type based on file extension
+		switch {
+		case ext == ".ts":
+			file.contentType = "video/mp2t"
+			segments = append(segments, file)
+		case relPath == "master.m3u8":
+			file.contentType = "application/vnd.apple.mpegurl"
+			master = append(master, file)
+		default:
+			file.contentType = "application/vnd.apple.mpegurl"
+			playlists = append(playlists, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list HLS files: %w", err)
+	}
+
+	concurrency := vp.config.UploadConcurrency
+	logger.Info("Uploading HLS files", "files", len(segments)+len(playlists)+len(master), "concurrency", concurrency)
+
+	var uploaded, skipped atomic.Int64
+	for _, batch := range [][]uploadFile{segments, playlists, master} {
+		if err := vp.uploadBatch(ctx, batch, concurrency, &uploaded, &skipped); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("All HLS files uploaded successfully", "uploaded", uploaded.Load(), "skipped", skipped.Load())
+	return nil
+}
+
+// uploadBatch envia os arquivos com um pool de até concurrency workers
+// A primeira falha cancela os uploads que ainda estão em andamento
+func (vp *VideoProcessor) uploadBatch(ctx context.Context, files []uploadFile, concurrency int, uploaded, skipped *atomic.Int64) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if concurrency > len(files) {
+		concurrency = len(files)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	pending := make(chan uploadFile)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range pending {
+				skip, err := vp.uploadObject(ctx, file)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("failed to upload %s: %w", file.key, err)
+						cancel() // Interrompe os uploads irmãos
+					})
+					continue
+				}
+				if skip {
+					skipped.Add(1)
+				} else {
+					uploaded.Add(1)
+				}
+			}
+		}()
+	}
+
+	// Distribui os arquivos, parando assim que algum upload falhar
+feed:
+	for _, file := range files {
+		select {
+		case pending <- file:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// uploadObject envia um arquivo, com novas tentativas e atraso exponencial
+// Retorna skip=true se o objeto já existia igual ao arquivo local
+func (vp *VideoProcessor) uploadObject(ctx context.Context, file uploadFile) (skip bool, err error) {
+	logger := logging.FromContext(ctx)
+
+	if vp.alreadyUploaded(ctx, file) {
+		logger.Debug("Skipping file already uploaded", "key", file.key, "bytes", file.size)
+		return true, nil
+	}
+
+	delay := vp.config.UploadRetryDelay
+	for attempt := 0; ; attempt++ {
+		logger.Debug("Uploading file", "path", file.path, "key", file.key, "attempt", attempt+1)
+		err = vp.storage.UploadFile(ctx, file.path, file.key, file.contentType)
+		if err == nil {
+			metrics.UploadedBytes.Add(float64(file.size))
+			return false, nil
+		}
+		if ctx.Err() != nil || attempt >= vp.config.UploadRetries {
+			return false, err
+		}
+
+		logger.Warn("Upload failed, retrying", "key", file.key, "attempt", attempt+1,
+			"max_attempts", vp.config.UploadRetries+1, "delay", delay.String(), "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, err
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxUploadRetryDelay)
+	}
+}
+
+// alreadyUploaded indica se o objeto já existe com o mesmo tamanho e ETag do arquivo
+// Qualquer erro na verificação leva a um novo upload, que sobrescreve o objeto
+func (vp *VideoProcessor) alreadyUploaded(ctx context.Context, file uploadFile) bool {
+	info, err := vp.storage.Stat(ctx, file.key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			logging.FromContext(ctx).Debug("Failed to stat object, uploading it again", "key", file.key, "error", err)
+		}
+		return false
+	}
+	if info.Size != file.size {
+		return false
+	}
+
+	etag, err := storage.FileETag(file.path)
+	if err != nil {
+		return false
+	}
+	return info.ETag == etag
+}
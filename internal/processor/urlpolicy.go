@@ -0,0 +1,281 @@
+package processor
+
+// Importações necessárias para a política de URLs das fontes
+import (
+	"context"        // Para o dialer das conexões
+	"errors"         // Para identificar violações da política
+	"fmt"            // Para formatação de strings
+	"net"            // Para verificar o endereço de cada conexão
+	"net/http"       // Para verificar os redirecionamentos
+	"net/netip"      // Para classificar endereços IP
+	neturl "net/url" // Para interpretar as URLs
+	"strings"        // Para manipulação de strings
+	"syscall"        // Para o hook de conexão do net.Dialer
+)
+
+// defaultMaxRedirects é o limite padrão de redirecionamentos de um download
+const defaultMaxRedirects = 5
+
+// errURLPolicy indica uma URL ou um endereço recusado pela política de download
+// O erro é permanente: a mesma mensagem vai ser recusada sempre
+var errURLPolicy = errors.New("source URL rejected by policy")
+
+// reservedPrefixes são faixas que não são roteáveis na internet além das que
+// netip já classifica (privadas, loopback, link-local, multicast)
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "Esta rede"
+	netip.MustParsePrefix("100.64.0.0/10"),  // CGNAT, usado por alguns clusters
+	netip.MustParsePrefix("192.0.0.0/24"),   // Atribuições de protocolo da IETF
+	netip.MustParsePrefix("192.88.99.0/24"), // Relay 6to4 (obsoleto)
+	netip.MustParsePrefix("198.18.0.0/15"),  // Testes de desempenho
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reservado, inclui o broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, pode apontar para IPv4 internos
+	netip.MustParsePrefix("64:ff9b:1::/48"), // NAT64 local
+	netip.MustParsePrefix("100::/64"),       // Descarte
+	netip.MustParsePrefix("2001::/23"),      // Atribuições de protocolo da IETF
+	netip.MustParsePrefix("2001:db8::/32"),  // Documentação
+	netip.MustParsePrefix("2002::/16"),      // 6to4, pode apontar para IPv4 internos
+	netip.MustParsePrefix("fec0::/10"),      // Site-local (obsoleto)
+}
+
+// URLPolicy restringe de onde as fontes podem ser baixadas, para que quem publica
+// na fila não consiga fazer o worker acessar endereços internos (SSRF), como os
+// metadados da nuvem, o MinIO ou serviços do cluster
+// A política vale para a URL da mensagem e para cada redirecionamento, e os
+// endereços são verificados depois da resolução de DNS, na hora de conectar
+type URLPolicy struct {
+	// AllowedSchemes são os esquemas aceitos (vazio = http e https)
+	// Fontes file:// dependem apenas de Config.AllowFileURLs
+	AllowedSchemes []string
+	// AllowedHosts, se não vazio, restringe os hosts aceitos
+	// Cada item é um nome ("videos.example.com"), um curinga de subdomínios
+	// ("*.example.com") ou uma rede em CIDR ("10.20.0.0/16")
+	// Redes listadas aqui podem ser acessadas mesmo sendo privadas
+	AllowedHosts []string
+	// DeniedHosts são recusados mesmo que estejam em AllowedHosts (mesmo formato)
+	DeniedHosts []string
+	// AllowPrivateIPs permite endereços privados, loopback, link-local e reservados
+	// Útil apenas em desenvolvimento
+	AllowPrivateIPs bool
+	// MaxRedirects é o número máximo de redirecionamentos (0 = 5; negativo = nenhum)
+	MaxRedirects int
+}
+
+// hostRules são as listas de hosts já interpretadas
+type hostRules struct {
+	names    []string       // Nomes exatos, em minúsculas
+	suffixes []string       // Sufixos dos curingas (".example.com")
+	networks []netip.Prefix // Redes em CIDR ou IPs isolados
+}
+
+// parseHostRules interpreta uma lista de hosts da política
+func parseHostRules(entries []string) (hostRules, error) {
+	var rules hostRules
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.HasPrefix(entry, "*."):
+			rules.suffixes = append(rules.suffixes, entry[1:])
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return rules, fmt.Errorf("invalid network %q: %w", entry, err)
+			}
+			rules.networks = append(rules.networks, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+				rules.networks = append(rules.networks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			rules.names = append(rules.names, strings.TrimSuffix(entry, "."))
+		}
+	}
+	return rules, nil
+}
+
+// empty indica se a lista não tem nenhuma regra
+func (r hostRules) empty() bool {
+	return len(r.names) == 0 && len(r.suffixes) == 0 && len(r.networks) == 0
+}
+
+// matchName indica se o nome do host está na lista (por nome ou curinga)
+func (r hostRules) matchName(host string) bool {
+	for _, name := range r.names {
+		if host == name {
+			return true
+		}
+	}
+	for _, suffix := range r.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddr indica se o endereço está numa das redes da lista
+func (r hostRules) matchAddr(addr netip.Addr) bool {
+	for _, network := range r.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate confere as listas de hosts da política
+func (p URLPolicy) Validate() error {
+	_, err := newURLGuard(p)
+	return err
+}
+
+// urlGuard aplica uma URLPolicy às URLs e às conexões dos downloads
+type urlGuard struct {
+	schemes      map[string]bool // Esquemas aceitos
+	allowed      hostRules       // Hosts permitidos (vazio = todos)
+	denied       hostRules       // Hosts recusados
+	allowPrivate bool            // Se endereços não públicos são aceitos
+	maxRedirects int             // Redirecionamentos seguidos (negativo = nenhum)
+	err          error           // Política inválida: recusa tudo
+}
+
+// newURLGuard valida a política e preenche os padrões
+func newURLGuard(p URLPolicy) (*urlGuard, error) {
+	g := &urlGuard{schemes: make(map[string]bool), allowPrivate: p.AllowPrivateIPs, maxRedirects: p.MaxRedirects}
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	for _, scheme := range schemes {
+		g.schemes[strings.ToLower(strings.TrimSpace(scheme))] = true
+	}
+	if g.maxRedirects == 0 {
+		g.maxRedirects = defaultMaxRedirects
+	}
+
+	var err error
+	if g.allowed, err = parseHostRules(p.AllowedHosts); err != nil {
+		return nil, fmt.Errorf("invalid allowed hosts: %w", err)
+	}
+	if g.denied, err = parseHostRules(p.DeniedHosts); err != nil {
+		return nil, fmt.Errorf("invalid denied hosts: %w", err)
+	}
+	return g, nil
+}
+
+// checkURL verifica o esquema e o nome do host de uma URL
+// Hosts que são IPs literais passam também pela verificação de endereço
+func (g *urlGuard) checkURL(u *neturl.URL) error {
+	if g.err != nil {
+		return g.err
+	}
+	if !g.schemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("%w: scheme %q is not allowed", errURLPolicy, u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: URL has no host", errURLPolicy)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.checkConn(host, addr)
+	}
+	if g.denied.matchName(host) {
+		return fmt.Errorf("%w: host %q is denied", errURLPolicy, host)
+	}
+	// Com lista de permitidos, o nome precisa estar nela ou o endereço resolvido
+	// precisa estar numa das redes permitidas (conferido ao conectar)
+	if !g.allowed.empty() && !g.allowed.matchName(host) && len(g.allowed.networks) == 0 {
+		return fmt.Errorf("%w: host %q is not in the allowed hosts", errURLPolicy, host)
+	}
+	return nil
+}
+
+// checkAddr verifica um endereço IP, literal ou resolvido pelo DNS
+func (g *urlGuard) checkAddr(addr netip.Addr) error {
+	if g.err != nil {
+		return g.err
+	}
+	addr = addr.Unmap()
+	if g.denied.matchAddr(addr) {
+		return fmt.Errorf("%w: address %s is denied", errURLPolicy, addr)
+	}
+	if g.allowed.matchAddr(addr) {
+		return nil // Redes permitidas explicitamente, mesmo que privadas
+	}
+	if !g.allowPrivate && !isPublicAddr(addr) {
+		return fmt.Errorf("%w: address %s is private, loopback, link-local or reserved", errURLPolicy, addr)
+	}
+	return nil
+}
+
+// checkConn verifica o endereço de cada conexão, depois da resolução de DNS
+// Com lista de permitidos, o host precisa estar nela pelo nome ou pelo endereço
+func (g *urlGuard) checkConn(host string, addr netip.Addr) error {
+	if err := g.checkAddr(addr); err != nil {
+		return err
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if g.allowed.empty() || g.allowed.matchName(host) {
+		return nil
+	}
+	if !g.allowed.matchAddr(addr.Unmap()) {
+		return fmt.Errorf("%w: host %q resolved to %s, outside the allowed hosts", errURLPolicy, host, addr.Unmap())
+	}
+	return nil
+}
+
+// dialContext conecta pelo dialer verificando o IP de cada tentativa de conexão,
+// já resolvido pelo DNS; recusar aqui cobre redirecionamentos e DNS rebinding
+func (g *urlGuard) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid address %q", errURLPolicy, address)
+		}
+		d := *dialer
+		d.Control = g.dialControl(host)
+		return d.DialContext(ctx, network, address)
+	}
+}
+
+// dialControl é o hook do net.Dialer chamado com o IP resolvido de cada conexão
+// host é o nome original, usado para conferir a lista de hosts permitidos
+func (g *urlGuard) dialControl(host string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		ip, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: invalid address %q", errURLPolicy, address)
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("%w: invalid address %q", errURLPolicy, address)
+		}
+		return g.checkConn(host, addr)
+	}
+}
+
+// checkRedirect aplica a política a cada redirecionamento e limita quantos são seguidos
+func (g *urlGuard) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > max(g.maxRedirects, 0) {
+		return fmt.Errorf("%w: too many redirects (maximum %d)", errURLPolicy, max(g.maxRedirects, 0))
+	}
+	return g.checkURL(req.URL)
+}
+
+// isPublicAddr indica se o endereço é roteável na internet
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
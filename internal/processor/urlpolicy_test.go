@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestURLGuardCheckURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy URLPolicy
+		url    string
+		allow  bool
+	}{
+		{"public host", URLPolicy{}, "https://videos.example.com/a.mp4", true},
+		{"scheme not allowed", URLPolicy{}, "ftp://videos.example.com/a.mp4", false},
+		{"custom schemes", URLPolicy{AllowedSchemes: []string{"https"}}, "http://videos.example.com/a.mp4", false},
+		{"no host", URLPolicy{}, "http:///a.mp4", false},
+
+		// IPs literais passam pela verificação de endereço já na URL
+		{"public ip literal", URLPolicy{}, "http://93.184.216.34/a.mp4", true},
+		{"loopback literal", URLPolicy{}, "http://127.0.0.1:9000/a.mp4", false},
+		{"private literal", URLPolicy{}, "http://10.0.0.5/a.mp4", false},
+		{"metadata literal", URLPolicy{}, "http://169.254.169.254/latest/meta-data", false},
+		{"ipv6 loopback literal", URLPolicy{}, "http://[::1]/a.mp4", false},
+		{"ipv4-mapped ipv6 literal", URLPolicy{}, "http://[::ffff:10.0.0.1]/a.mp4", false},
+		{"cgnat literal", URLPolicy{}, "http://100.64.1.1/a.mp4", false},
+		{"private allowed in dev", URLPolicy{AllowPrivateIPs: true}, "http://127.0.0.1/a.mp4", true},
+
+		// Listas de hosts
+		{"allowed name", URLPolicy{AllowedHosts: []string{"videos.example.com"}}, "https://videos.example.com/a", true},
+		{"allowed name with trailing dot", URLPolicy{AllowedHosts: []string{"videos.example.com"}}, "https://VIDEOS.example.com./a", true},
+		{"name not allowed", URLPolicy{AllowedHosts: []string{"videos.example.com"}}, "https://evil.example.org/a", false},
+		{"wildcard", URLPolicy{AllowedHosts: []string{"*.example.com"}}, "https://cdn.eu.example.com/a", true},
+		{"wildcard does not match apex", URLPolicy{AllowedHosts: []string{"*.example.com"}}, "https://example.com/a", false},
+		{"denied wins over allowed", URLPolicy{AllowedHosts: []string{"*.example.com"}, DeniedHosts: []string{"internal.example.com"}}, "https://internal.example.com/a", false},
+		{"denied network literal", URLPolicy{DeniedHosts: []string{"93.184.0.0/16"}}, "http://93.184.216.34/a", false},
+
+		// Redes em CIDR liberam endereços privados e adiam a checagem de nomes para a conexão
+		{"allowed private network literal", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "http://10.20.1.2/a", true},
+		{"literal outside allowed network", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "http://10.21.1.2/a", false},
+		{"public literal outside allowed network", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "http://93.184.216.34/a", false},
+		{"name with network allow-list", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "http://minio.internal/a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newURLGuard(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = guard.checkURL(u)
+			if tt.allow && err != nil {
+				t.Errorf("expected %s to be allowed, got %v", tt.url, err)
+			}
+			if !tt.allow && !errors.Is(err, errURLPolicy) {
+				t.Errorf("expected %s to be rejected by policy, got %v", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestURLGuardCheckConn(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy URLPolicy
+		host   string
+		addr   string
+		allow  bool
+	}{
+		{"public address", URLPolicy{}, "videos.example.com", "93.184.216.34", true},
+		// DNS rebinding: o nome é público, mas resolve para um endereço interno
+		{"name resolving to loopback", URLPolicy{}, "videos.example.com", "127.0.0.1", false},
+		{"name resolving to private", URLPolicy{}, "videos.example.com", "192.168.1.10", false},
+		{"name resolving to metadata", URLPolicy{}, "videos.example.com", "169.254.169.254", false},
+		{"name resolving to ula", URLPolicy{}, "videos.example.com", "fd00::1", false},
+		{"name resolving to nat64", URLPolicy{}, "videos.example.com", "64:ff9b::a00:1", false},
+		{"allowed network", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "minio.internal", "10.20.3.4", true},
+		{"outside allowed network", URLPolicy{AllowedHosts: []string{"10.20.0.0/16"}}, "minio.internal", "10.30.3.4", false},
+		{"allowed name still checks address", URLPolicy{AllowedHosts: []string{"videos.example.com"}}, "videos.example.com", "10.0.0.1", false},
+		{"allowed name public address", URLPolicy{AllowedHosts: []string{"videos.example.com"}}, "videos.example.com", "93.184.216.34", true},
+		{"denied address", URLPolicy{DeniedHosts: []string{"93.184.216.34"}}, "videos.example.com", "93.184.216.34", false},
+		{"private allowed in dev", URLPolicy{AllowPrivateIPs: true}, "localhost", "127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newURLGuard(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = guard.checkConn(tt.host, netip.MustParseAddr(tt.addr))
+			if tt.allow && err != nil {
+				t.Errorf("expected %s (%s) to be allowed, got %v", tt.host, tt.addr, err)
+			}
+			if !tt.allow && !errors.Is(err, errURLPolicy) {
+				t.Errorf("expected %s (%s) to be rejected by policy, got %v", tt.host, tt.addr, err)
+			}
+		})
+	}
+}
+
+func TestURLGuardCheckRedirect(t *testing.T) {
+	newRequest := func(rawURL string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, rawURL, nil)
+	}
+	via := func(n int) []*http.Request {
+		out := make([]*http.Request, n)
+		for i := range out {
+			out[i] = newRequest("https://videos.example.com/a.mp4")
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		policy URLPolicy
+		target string
+		hops   int
+		allow  bool
+	}{
+		{"first redirect", URLPolicy{}, "https://cdn.example.com/a.mp4", 1, true},
+		{"at the default limit", URLPolicy{}, "https://cdn.example.com/a.mp4", 5, true},
+		{"over the default limit", URLPolicy{}, "https://cdn.example.com/a.mp4", 6, false},
+		{"custom limit", URLPolicy{MaxRedirects: 2}, "https://cdn.example.com/a.mp4", 3, false},
+		{"redirects disabled", URLPolicy{MaxRedirects: -1}, "https://cdn.example.com/a.mp4", 1, false},
+		{"redirect to metadata", URLPolicy{}, "http://169.254.169.254/latest/meta-data", 1, false},
+		{"redirect to other scheme", URLPolicy{}, "file:///etc/passwd", 1, false},
+		{"redirect outside allow-list", URLPolicy{AllowedHosts: []string{"*.example.com"}}, "https://evil.example.org/a", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newURLGuard(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = guard.checkRedirect(newRequest(tt.target), via(tt.hops))
+			if tt.allow && err != nil {
+				t.Errorf("expected redirect to be allowed, got %v", err)
+			}
+			if !tt.allow && !errors.Is(err, errURLPolicy) {
+				t.Errorf("expected redirect to be rejected by policy, got %v", err)
+			}
+		})
+	}
+}
+
+func TestURLPolicyValidate(t *testing.T) {
+	if err := (URLPolicy{AllowedHosts: []string{"10.0.0.0/8", "*.example.com", "::1"}}).Validate(); err != nil {
+		t.Errorf("valid policy: %v", err)
+	}
+	if err := (URLPolicy{AllowedHosts: []string{"10.0.0.0/99"}}).Validate(); err == nil {
+		t.Errorf("expected an error for an invalid network")
+	}
+	if err := (URLPolicy{DeniedHosts: []string{"not-an-ip/8"}}).Validate(); err == nil {
+		t.Errorf("expected an error for an invalid denied network")
+	}
+}
+
+// O cliente de download recusa conexões com endereços internos na hora de
+// conectar, mesmo quando a URL usa um nome (aqui, "localhost")
+func TestDownloadClientBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	target := "http://localhost:" + u.Port() + "/"
+
+	guard, err := newURLGuard(URLPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newHTTPClient(DownloadConfig{}.withDefaults(), guard)
+	if resp, err := client.Get(target); !errors.Is(err, errURLPolicy) {
+		if err == nil {
+			resp.Body.Close()
+		}
+		t.Fatalf("expected the connection to be rejected by policy, got %v", err)
+	}
+
+	guard, err = newURLGuard(URLPolicy{AllowPrivateIPs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = newHTTPClient(DownloadConfig{}.withDefaults(), guard)
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("expected the connection to be allowed, got %v", err)
+	}
+	resp.Body.Close()
+}
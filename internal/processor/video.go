@@ -4,39 +4,250 @@ package processor
 
 // Importações necessárias para o processamento de vídeos
 import (
-	"fmt"                               // Para formatação de strings
-	"io"                                // Para operações de entrada/saída
-	"log"                               // Para logging
-	"ms-videos/internal/queue"          // Para estruturas de mensagens da fila
-	"ms-videos/internal/storage"        // Para cliente de armazenamento
-	"net/http"                          // Para downloads HTTP
-	"os"                                // Para operações do sistema operacional
-	"os/exec"                           // Para execução de comandos externos (ffmpeg)
-	"path/filepath"                     // Para manipulação de caminhos de arquivos
-	"strings"                           // Para manipulação de strings
+	"context"                    // Para cancelamento e timeouts das etapas
+	"errors"                     // Para identificar jobs cancelados
+	"fmt"                        // Para formatação de strings
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para o logger de cada job
+	"ms-videos/internal/metrics" // Para as métricas Prometheus
+	"ms-videos/internal/queue"   // Para estruturas de mensagens da fila
+	"ms-videos/internal/storage" // Para o armazenamento dos arquivos HLS
+	"net/http"                   // Para downloads HTTP
+	"os"                         // Para operações do sistema operacional
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"runtime"                    // Para descobrir o número de CPUs
+	"strings"                    // Para manipulação de strings
+	"sync"                       // Para o registro de jobs em andamento
+	"time"                       // Para os timeouts das etapas
 )
 
 // VideoProcessor é uma struct que encapsula a lógica de processamento de vídeos
 // Em Go, structs são como classes em outras linguagens
 type VideoProcessor struct {
-	// storageClient é um ponteiro para o cliente MinIO
-	// O * indica que é um ponteiro, não uma cópia da struct
-	storageClient *storage.MinIOClient
+	// storage é o armazenamento onde os arquivos HLS são publicados
+	// (MinIO ou diretório local, veja storage.Backend)
+	storage storage.Backend
+	// config guarda as opções de processamento (perfis de encoding etc.)
+	config Config
+	// guard aplica a política de URLs dos downloads (DownloadConfig.Policy)
+	guard *urlGuard
+	// httpClient baixa as fontes, com os timeouts de DownloadConfig
+	httpClient *http.Client
+	// fetchers obtêm as fontes, pelo esquema da URL (veja Fetcher)
+	fetchers map[string]Fetcher
+	// progress guarda o progresso do ffmpeg dos jobs em encoding
+	progress *progressTracker
+	// running guarda a função de cancelamento de cada job em andamento
+	running   map[string]context.CancelCauseFunc
+	runningMu sync.Mutex
+}
+
+// Config reúne as opções configuráveis do processador
+type Config struct {
+	Profiles           *ProfileSet    // Perfis de encoding disponíveis (nil = perfis embutidos)
+	EncodeMode         string         // EncodeSinglePass (padrão) ou EncodePerRendition
+	Threads            int            // Orçamento total de threads do ffmpeg (0 = número de CPUs)
+	MaxParallelEncodes int            // Máximo de ffmpeg simultâneos no modo por variante (0 = uma por variante)
+	Timeouts           Timeouts       // Limites de tempo de cada etapa
+	Download           DownloadConfig // Timeouts, limite de tamanho e retentativas dos downloads
+	UploadConcurrency  int            // Uploads simultâneos de arquivos HLS (0 = 8)
+	UploadRetries      int            // Novas tentativas de cada upload que falha (0 = nenhuma)
+	UploadRetryDelay   time.Duration  // Atraso antes da 1ª nova tentativa, dobra a cada uma (0 = 1s)
+	Events             EventPublisher // Destino dos eventos do ciclo de vida (nil = sem eventos)
+	// AllowFileURLs permite fontes file:// de qualquer diretório local
+	// Só deve ser ligado quando as mensagens são confiáveis (modo CLI); no serviço,
+	// use DownloadConfig.FileRoots para liberar apenas os volumes montados
+	AllowFileURLs bool
+	// Fetchers registra fetchers por esquema de URL, além dos embutidos
+	// (http, https, s3 e file), que também podem ser substituídos
+	Fetchers map[string]Fetcher
+	// IsCanceled é consultada antes de iniciar um job; jobs cancelados enquanto
+	// estavam na fila são descartados sem processamento (nil = nunca)
+	IsCanceled func(id string) bool
+}
+
+// Timeouts define o tempo máximo de cada etapa do processamento (0 = sem limite)
+type Timeouts struct {
+	Download time.Duration // Download da fonte
+	Probe    time.Duration // Cada execução do ffprobe
+	Encode   time.Duration // Geração de todas as variantes
+	Upload   time.Duration // Upload de todos os arquivos HLS
 }
 
 // NewVideoProcessor é uma função construtora que cria uma nova instância de VideoProcessor
 // Em Go, é comum usar funções New* como construtores
 // O & retorna o endereço de memória da struct (cria um ponteiro)
-func NewVideoProcessor(storageClient *storage.MinIOClient) *VideoProcessor {
-	return &VideoProcessor{
-		storageClient: storageClient,
+func NewVideoProcessor(store storage.Backend, config Config) *VideoProcessor {
+	if config.Profiles == nil {
+		config.Profiles = DefaultProfiles()
+	}
+	if config.EncodeMode == "" {
+		config.EncodeMode = EncodeSinglePass
+	}
+	if config.Threads <= 0 {
+		config.Threads = runtime.NumCPU()
+	}
+	config.Download = config.Download.withDefaults()
+	if config.UploadConcurrency <= 0 {
+		config.UploadConcurrency = defaultUploadConcurrency
 	}
+	if config.UploadRetryDelay <= 0 {
+		config.UploadRetryDelay = defaultUploadRetryDelay
+	}
+	guard, err := newURLGuard(config.Download.Policy)
+	if err != nil {
+		// Política inválida (que URLPolicy.Validate teria apontado): recusa todos os downloads
+		slog.Error("Invalid download URL policy, rejecting all downloads", "error", err)
+		guard = &urlGuard{err: fmt.Errorf("%w: %v", errURLPolicy, err)}
+	}
+	vp := &VideoProcessor{
+		storage:    store,
+		config:     config,
+		guard:      guard,
+		httpClient: newHTTPClient(config.Download, guard),
+		progress:   newProgressTracker(),
+		running:    make(map[string]context.CancelCauseFunc),
+	}
+	vp.fetchers = vp.newFetchers()
+	return vp
 }
 
 // ProcessVideo controla o fluxo de trabalho para processar um vídeo incluindo
 // download, processamento de resoluções, criação de playlist mestre e upload
-func (vp *VideoProcessor) ProcessVideo(msg queue.VideoMessage) error {
-	log.Printf("Starting processing video %s", msg.ID)
+// Cancelar ctx interrompe a etapa em andamento, inclusive o ffmpeg
+// Cada etapa publica um evento do ciclo de vida, terminando em completed ou failed
+// Mensagens inválidas (veja queue.VideoMessage.Validate) falham com erro permanente
+// antes de qualquer trabalho
+func (vp *VideoProcessor) ProcessVideo(ctx context.Context, msg queue.VideoMessage) error {
+	if err := msg.Validate(); err != nil {
+		vp.reject(ctx, msg, err)
+		return queue.Permanent(err)
+	}
+
+	j := &job{msg: msg, log: logging.FromContext(ctx).With("job_id", msg.ID)}
+
+	if vp.config.IsCanceled != nil && vp.config.IsCanceled(msg.ID) {
+		j.log.Info("Skipping video canceled while queued", "stage", "received")
+		vp.emit(queue.JobEvent{Type: queue.EventCanceled, JobID: msg.ID})
+		return queue.ErrCanceled
+	}
+
+	profileName := msg.Profile
+	if profileName == "" {
+		profileName = vp.config.Profiles.Default
+	}
+
+	j.log.Info("Starting processing video", "stage", "received", "profile", profileName)
+	started := time.Now()
+	vp.emit(queue.JobEvent{Type: queue.EventReceived, JobID: msg.ID})
+
+	metrics.JobsReceived.Inc()
+	metrics.JobsInFlight.Inc()
+	metrics.JobStarted.WithLabelValues(msg.ID, profileName).Set(float64(started.Unix()))
+	defer func() {
+		metrics.JobsInFlight.Dec()
+		metrics.JobStarted.DeleteLabelValues(msg.ID, profileName)
+	}()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	vp.track(msg.ID, cancel)
+	defer vp.untrack(msg.ID)
+
+	defer vp.progress.remove(msg.ID)
+	err := vp.process(ctx, j)
+	if err != nil {
+		metrics.JobsFailed.WithLabelValues(queue.ErrorClass(err)).Inc()
+	}
+	if errors.Is(err, queue.ErrCanceled) {
+		j.log.Info("Video was canceled", "stage", "canceled", "error", err)
+		vp.emit(queue.JobEvent{
+			Type:           queue.EventCanceled,
+			JobID:          msg.ID,
+			ElapsedSeconds: time.Since(started).Seconds(),
+		})
+		return err
+	}
+	if err != nil {
+		j.log.Error("Failed to process video", "stage", "failed", "error", err, "error_class", queue.ErrorClass(err))
+		vp.emit(queue.JobEvent{
+			Type:           queue.EventFailed,
+			JobID:          msg.ID,
+			ElapsedSeconds: time.Since(started).Seconds(),
+			Error:          logging.Redact(err.Error()),
+			ErrorClass:     queue.ErrorClass(err),
+		})
+		return err
+	}
+
+	metrics.JobsSucceeded.Inc()
+	vp.emit(queue.JobEvent{
+		Type:            queue.EventCompleted,
+		JobID:           msg.ID,
+		ManifestKeys:    manifestKeys(j),
+		Renditions:      renditionInfos(j),
+		Source:          j.source,
+		DurationSeconds: j.media.Duration.Seconds(),
+		ElapsedSeconds:  time.Since(started).Seconds(),
+	})
+	j.log.Info("Successfully processed video", "stage", "completed",
+		"renditions", len(j.renditions), "elapsed", time.Since(started).String())
+	return nil
+}
+
+// reject registra uma mensagem recusada por Validate como um job que falhou
+// Se o ID for válido, a falha é publicada como evento, para que quem submeteu
+// o job (como a API) não o veja na fila para sempre
+func (vp *VideoProcessor) reject(ctx context.Context, msg queue.VideoMessage, err error) {
+	metrics.JobsReceived.Inc()
+	metrics.JobsFailed.WithLabelValues(queue.ErrorClassPermanent).Inc()
+
+	logger := logging.FromContext(ctx)
+	if !queue.ValidID.MatchString(msg.ID) {
+		logger.Warn("Rejecting invalid video message", "stage", "received", "error", err)
+		return
+	}
+	logger.With("job_id", msg.ID).Warn("Rejecting invalid video message", "stage", "received", "error", err)
+	vp.emit(queue.JobEvent{
+		Type:       queue.EventFailed,
+		JobID:      msg.ID,
+		Error:      logging.Redact(err.Error()),
+		ErrorClass: queue.ErrorClassPermanent,
+	})
+}
+
+// Cancel interrompe um job em andamento nesta instância, inclusive o ffmpeg
+// Retorna false se o job não estiver sendo processado aqui
+func (vp *VideoProcessor) Cancel(id string) bool {
+	vp.runningMu.Lock()
+	defer vp.runningMu.Unlock()
+
+	cancel, ok := vp.running[id]
+	if ok {
+		cancel(queue.ErrCanceled)
+	}
+	return ok
+}
+
+// track registra a função de cancelamento de um job em andamento
+func (vp *VideoProcessor) track(id string, cancel context.CancelCauseFunc) {
+	vp.runningMu.Lock()
+	defer vp.runningMu.Unlock()
+	vp.running[id] = cancel
+}
+
+// untrack remove o job do registro e libera o seu contexto
+func (vp *VideoProcessor) untrack(id string) {
+	vp.runningMu.Lock()
+	defer vp.runningMu.Unlock()
+	if cancel, ok := vp.running[id]; ok {
+		cancel(nil)
+		delete(vp.running, id)
+	}
+}
+
+// process executa as etapas do job, preenchendo j conforme avança
+func (vp *VideoProcessor) process(ctx context.Context, j *job) error {
+	msg := j.msg
 
 	// Cria um diretório temporário para armazenar arquivos durante o processamento
 	// O padrão "video_{ID}_*" ajuda a identificar facilmente os arquivos temporários
@@ -44,183 +255,144 @@ func (vp *VideoProcessor) ProcessVideo(msg queue.VideoMessage) error {
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
+	j.tempDir = tempDir
 	// defer com função anônima para limpeza automática dos arquivos temporários
 	// Isso garante que os arquivos serão removidos mesmo se houver erro
 	defer func() {
-		log.Printf("Cleaning up temporary files for video %s", msg.ID)
+		logging.FromContext(j.stage(ctx, "cleanup")).Debug("Cleaning up temporary files", "dir", tempDir)
 		os.RemoveAll(tempDir) // Remove recursivamente o diretório e conteúdo
 	}()
 
+	// Resolve o perfil de encoding pedido na mensagem (ou o padrão)
+	j.profile, err = vp.config.Profiles.Get(msg.Profile)
+	if err != nil {
+		return queue.Permanent(err)
+	}
+
 	// Faz o download do vídeo original da URL fornecida
-	originalPath, err := vp.downloadVideo(msg.URL, tempDir, msg.Filename)
+	vp.emit(queue.JobEvent{Type: queue.EventDownloading, JobID: msg.ID})
+	stageStarted := time.Now()
+	stageCtx, cancel := withTimeout(j.stage(ctx, metrics.StageDownload), vp.config.Timeouts.Download)
+	j.sourcePath, j.source, err = vp.downloadVideo(stageCtx, msg, tempDir)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("failed to download video: %w", err)
+		return fmt.Errorf("failed to download video: %w", stageError(stageCtx, err))
 	}
+	observeStage(metrics.StageDownload, "", stageStarted)
 
-	// Processa o vídeo em diferentes resoluções para streaming adaptativo
+	// Inspeciona a fonte para conhecer resolução, duração, rotação e streams
+	probeCtx := j.stage(ctx, metrics.StageProbe)
+	logging.FromContext(probeCtx).Info("Probing video")
+	stageStarted = time.Now()
+	j.media, err = vp.probe(probeCtx, j.sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to probe video: %w", err)
+	}
+	observeStage(metrics.StageProbe, "", stageStarted)
+	logging.FromContext(probeCtx).Info("Probed video",
+		"width", j.media.Width, "height", j.media.Height, "fps", j.media.FrameRate,
+		"duration", j.media.Duration.String(), "rotation", j.media.Rotation)
+
+	// Monta a escada de resoluções descartando as que fariam upscale da fonte
 	// HLS permite que o player escolha a melhor qualidade baseada na conexão
-	resolutions := []string{"1080p", "720p", "480p", "360p"}
-	for _, resolution := range resolutions { // range itera sobre cada elemento do slice
-		log.Printf("Processing video %s to %s resolution", msg.ID, resolution)
-		err := vp.processResolution(originalPath, tempDir, msg.ID, resolution)
+	j.rungs = selectRungs(j.profile, j.media)
+	stageCtx, cancel = withTimeout(j.stage(ctx, metrics.StageEncode), vp.config.Timeouts.Encode)
+	err = vp.encodeRenditions(stageCtx, j)
+	cancel()
+	if err != nil {
+		return stageError(stageCtx, err)
+	}
+
+	// Mede o que foi realmente produzido (bitrates, dimensões, fps e codecs)
+	// para que a playlist mestre anuncie valores reais e não nominais
+	measureCtx := j.stage(ctx, "measure")
+	for _, rung := range j.rungs {
+		stageCtx, cancel := withTimeout(measureCtx, vp.config.Timeouts.Probe)
+		rendition, err := measureRendition(stageCtx, filepath.Join(tempDir, "hls"), rung.Name)
+		cancel()
 		if err != nil {
-			return fmt.Errorf("failed to process %s resolution: %w", resolution, err)
+			return fmt.Errorf("failed to measure %s rendition: %w", rung.Name, stageError(stageCtx, err))
 		}
+		j.renditions = append(j.renditions, *rendition)
 	}
 
 	// Cria a playlist mestre que referencia todas as resoluções
 	// Esta é a entrada principal para o streaming HLS
-	log.Printf("Creating master playlist for video %s", msg.ID)
-	err = vp.createMasterPlaylist(tempDir, msg.ID, resolutions)
+	err = vp.createMasterPlaylist(tempDir, j.renditions)
 	if err != nil {
 		return fmt.Errorf("failed to create master playlist: %w", err)
 	}
+	logging.FromContext(j.stage(ctx, "playlist")).Info("Created master playlist", "renditions", len(j.renditions))
 
 	// Faz upload de todos os arquivos HLS gerados para o armazenamento
 	// Isso inclui playlists (.m3u8) e segmentos de vídeo (.ts)
-	log.Printf("Uploading HLS files for video %s", msg.ID)
-	err = vp.uploadHLSFiles(tempDir, msg.ID)
+	vp.emit(queue.JobEvent{Type: queue.EventUploading, JobID: msg.ID})
+	stageStarted = time.Now()
+	stageCtx, cancel = withTimeout(j.stage(ctx, metrics.StageUpload), vp.config.Timeouts.Upload)
+	err = vp.uploadHLSFiles(stageCtx, tempDir, msg.ID)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("failed to upload HLS files: %w", err)
+		return fmt.Errorf("failed to upload HLS files: %w", stageError(stageCtx, err))
 	}
+	observeStage(metrics.StageUpload, "", stageStarted)
 
-	log.Printf("Successfully processed video %s", msg.ID)
 	return nil
 }
 
-func (vp *VideoProcessor) downloadVideo(url, tempDir, filename string) (string, error) {
-	log.Printf("Downloading video from URL: %s", url)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to download video: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download video: status %d", resp.StatusCode)
-	}
-
-	filePath := filepath.Join(tempDir, filename)
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to save video: %w", err)
+// manifestKeys lista as chaves das playlists publicadas: a mestre e a de cada variante
+func manifestKeys(j *job) []string {
+	keys := []string{j.msg.ID + "/master.m3u8"}
+	for _, rendition := range j.renditions {
+		keys = append(keys, j.msg.ID+"/"+rendition.Playlist)
 	}
-
-	log.Printf("Video downloaded successfully to %s", filePath)
-	return filePath, nil
+	return keys
 }
 
-func (vp *VideoProcessor) processResolution(inputPath, tempDir, videoID, resolution string) error {
-	var height string
-	switch resolution {
-	case "1080p":
-		height = "1080"
-	case "720p":
-		height = "720"
-	case "480p":
-		height = "480"
-	case "360p":
-		height = "360"
-	default:
-		return fmt.Errorf("unsupported resolution: %s", resolution)
-	}
-
-	// Create output directory for this resolution
-	outputDir := filepath.Join(tempDir, "hls", resolution)
-	err := os.MkdirAll(outputDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Generate HLS files using ffmpeg
-	playlistPath := filepath.Join(outputDir, "playlist.m3u8")
-	cmd := exec.Command("ffmpeg",
-		"-i", inputPath,
-		"-vf", fmt.Sprintf("scale=-2:%s", height), // Maintain aspect ratio
-		"-c:v", "libx264",
-		"-c:a", "aac",
-		"-hls_time", "10", // 10 second segments
-		"-hls_list_size", "0", // Keep all segments in playlist
-		"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"),
-		"-f", "hls",
-		playlistPath,
-	)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	err = cmd.Run()
-	if err != nil {
-		return fmt.Errorf("ffmpeg failed for %s: %w", resolution, err)
+// renditionInfos descreve as variantes publicadas para o evento completed
+func renditionInfos(j *job) []queue.RenditionInfo {
+	infos := make([]queue.RenditionInfo, 0, len(j.renditions))
+	for _, r := range j.renditions {
+		infos = append(infos, queue.RenditionInfo{
+			Name:             r.Name,
+			Width:            r.Width,
+			Height:           r.Height,
+			FrameRate:        r.FrameRate,
+			PeakBandwidth:    r.PeakBandwidth,
+			AverageBandwidth: r.AverageBandwidth,
+			Codecs:           r.Codecs,
+			Playlist:         j.msg.ID + "/" + r.Playlist,
+		})
 	}
-
-	log.Printf("Successfully processed video to %s resolution", resolution)
-	return nil
+	return infos
 }
 
-func (vp *VideoProcessor) uploadHLSFiles(tempDir, videoID string) error {
-	hlsDir := filepath.Join(tempDir, "hls")
-
-	// Walk through all HLS files and upload them
-	err := filepath.Walk(hlsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			return nil
-		}
-
-		// Only upload .m3u8 and .ts files
-		ext := filepath.Ext(path)
-		if ext != ".m3u8" && ext != ".ts" {
-			return nil
-		}
-
-		// Generate object key (relative path from hls directory)
-		relPath, err := filepath.Rel(hlsDir, path)
-		if err != nil {
-			return err
-		}
-
-		// Convert Windows paths to Unix-style for object keys
-		relPath = strings.ReplaceAll(relPath, "\\", "/")
-		objectKey := fmt.Sprintf("%s/%s", videoID, relPath)
-
-		// Set content type based on file extension
-		var contentType string
-		switch ext {
-		case ".m3u8":
-			contentType = "application/vnd.apple.mpegurl"
-		case ".ts":
-			contentType = "video/mp2t"
-		}
-
-		log.Printf("Uploading file: %s as %s", path, objectKey)
-		err = vp.storageClient.UploadFile(path, objectKey, contentType)
-		if err != nil {
-			return fmt.Errorf("failed to upload %s: %w", objectKey, err)
-		}
+// observeStage registra a duração de uma etapa concluída com sucesso
+func observeStage(stage, rendition string, started time.Time) {
+	metrics.StageDuration.WithLabelValues(stage, rendition).Observe(time.Since(started).Seconds())
+}
 
-		return nil
-	})
+// probe executa o ffprobe sobre a fonte respeitando o timeout da etapa
+// Se o ffprobe não consegue ler a fonte, o arquivo é inválido e o erro é permanente
+func (vp *VideoProcessor) probe(ctx context.Context, path string) (*MediaInfo, error) {
+	ctx, cancel := withTimeout(ctx, vp.config.Timeouts.Probe)
+	defer cancel()
 
+	media, err := probeVideo(ctx, path)
 	if err != nil {
-		return fmt.Errorf("failed to upload HLS files: %w", err)
+		if ctx.Err() != nil {
+			return nil, stageError(ctx, err)
+		}
+		return nil, queue.Permanent(err)
 	}
-
-	log.Printf("All HLS files uploaded successfully for video %s", videoID)
-	return nil
+	return media, nil
 }
 
-func (vp *VideoProcessor) createMasterPlaylist(tempDir, videoID string, resolutions []string) error {
+// masterPlaylistVersion é a versão do protocolo HLS declarada na master playlist
+// AVERAGE-BANDWIDTH e FRAME-RATE em #EXT-X-STREAM-INF exigem a versão 7
+// (validadores estritos, como o mediastreamvalidator da Apple, recusam versões menores)
+const masterPlaylistVersion = 7
+
+func (vp *VideoProcessor) createMasterPlaylist(tempDir string, renditions []Rendition) error {
 	hlsDir := filepath.Join(tempDir, "hls")
 	masterPath := filepath.Join(hlsDir, "master.m3u8")
 
@@ -235,29 +407,24 @@ func (vp *VideoProcessor) createMasterPlaylist(tempDir, videoID string, resoluti
 	if err != nil {
 		return err
 	}
-	_, err = file.WriteString("#EXT-X-VERSION:3\n\n")
+	_, err = fmt.Fprintf(file, "#EXT-X-VERSION:%d\n\n", masterPlaylistVersion)
 	if err != nil {
 		return err
 	}
 
-	// Add each resolution as a stream variant
-	for _, resolution := range resolutions {
-		bandwidth, width, height := getStreamInfo(resolution)
-		
-		// Write stream info
-		streamInfo := fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n", bandwidth, width, height)
-		_, err = file.WriteString(streamInfo)
+	// Add each rendition as a stream variant, using the measured values
+	for _, rendition := range renditions {
+		_, err = file.WriteString(streamInfTag(rendition))
 		if err != nil {
 			return err
 		}
-		
+
 		// Write playlist path
-		playlistPath := fmt.Sprintf("%s/playlist.m3u8\n", resolution)
-		_, err = file.WriteString(playlistPath)
+		_, err = file.WriteString(rendition.Playlist + "\n")
 		if err != nil {
 			return err
 		}
-		
+
 		// Add blank line for readability
 		_, err = file.WriteString("\n")
 		if err != nil {
@@ -265,22 +432,22 @@ func (vp *VideoProcessor) createMasterPlaylist(tempDir, videoID string, resoluti
 		}
 	}
 
-	log.Printf("Master playlist created successfully for video %s", videoID)
 	return nil
 }
 
-func getStreamInfo(resolution string) (bandwidth, width, height int) {
-	switch resolution {
-	case "1080p":
-		return 5000000, 1920, 1080 // 5 Mbps
-	case "720p":
-		return 3000000, 1280, 720  // 3 Mbps
-	case "480p":
-		return 1500000, 854, 480   // 1.5 Mbps
-	case "360p":
-		return 800000, 640, 360    // 800 Kbps
-	default:
-		return 1000000, 640, 360   // Default
+// streamInfTag monta a linha #EXT-X-STREAM-INF de uma variante
+// CODECS e FRAME-RATE só são escritos quando puderam ser medidos
+func streamInfTag(r Rendition) string {
+	attrs := []string{
+		fmt.Sprintf("BANDWIDTH=%d", r.PeakBandwidth),
+		fmt.Sprintf("AVERAGE-BANDWIDTH=%d", r.AverageBandwidth),
+		fmt.Sprintf("RESOLUTION=%dx%d", r.Width, r.Height),
 	}
+	if r.FrameRate > 0 {
+		attrs = append(attrs, fmt.Sprintf("FRAME-RATE=%.3f", r.FrameRate))
+	}
+	if r.Codecs != "" {
+		attrs = append(attrs, fmt.Sprintf("CODECS=\"%s\"", r.Codecs))
+	}
+	return "#EXT-X-STREAM-INF:" + strings.Join(attrs, ",") + "\n"
 }
-
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"ms-videos/internal/queue"
+)
+
+// eventRecorder guarda os eventos publicados
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []queue.JobEvent
+}
+
+func (r *eventRecorder) PublishEvent(_ context.Context, ev queue.JobEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return nil
+}
+
+// Uma mensagem inválida com ID válido falha o job, para que ele não fique na fila da API
+func TestProcessVideoRejectsInvalidMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   queue.VideoMessage
+		event bool
+	}{
+		{"invalid filename", queue.VideoMessage{ID: "video-01", URL: "https://example.com/a.mp4", Filename: "../a.mp4"}, true},
+		{"invalid id", queue.VideoMessage{ID: "../video", URL: "https://example.com/a.mp4", Filename: "a.mp4"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &eventRecorder{}
+			vp := NewVideoProcessor(nil, Config{Events: events})
+
+			err := vp.ProcessVideo(context.Background(), tt.msg)
+			if !queue.IsPermanent(err) || !errors.Is(err, queue.ErrInvalidMessage) {
+				t.Fatalf("expected a permanent ErrInvalidMessage, got %v", err)
+			}
+
+			if !tt.event {
+				if len(events.events) != 0 {
+					t.Errorf("unexpected events %+v", events.events)
+				}
+				return
+			}
+			if len(events.events) != 1 {
+				t.Fatalf("got %d events, want 1: %+v", len(events.events), events.events)
+			}
+			ev := events.events[0]
+			if ev.Type != queue.EventFailed || ev.JobID != tt.msg.ID || ev.ErrorClass != queue.ErrorClassPermanent || ev.Error == "" {
+				t.Errorf("event = %+v", ev)
+			}
+		})
+	}
+}
+
+func TestCreateMasterPlaylist(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "hls"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	renditions := []Rendition{
+		{Name: "720p", Width: 1280, Height: 720, FrameRate: 29.97, PeakBandwidth: 3280000, AverageBandwidth: 2950000,
+			Codecs: "avc1.64001f,mp4a.40.2", Playlist: "720p/playlist.m3u8"},
+		// Sem frame rate e codecs medidos, os atributos são omitidos
+		{Name: "360p", Width: 640, Height: 360, PeakBandwidth: 900000, AverageBandwidth: 800000, Playlist: "360p/playlist.m3u8"},
+	}
+
+	vp := NewVideoProcessor(nil, Config{})
+	if err := vp.createMasterPlaylist(tempDir, renditions); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "hls", "master.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// AVERAGE-BANDWIDTH e FRAME-RATE exigem a versão 7 do protocolo
+	want := `#EXTM3U
+#EXT-X-VERSION:7
+
+#EXT-X-STREAM-INF:BANDWIDTH=3280000,AVERAGE-BANDWIDTH=2950000,RESOLUTION=1280x720,FRAME-RATE=29.970,CODECS="avc1.64001f,mp4a.40.2"
+720p/playlist.m3u8
+
+#EXT-X-STREAM-INF:BANDWIDTH=900000,AVERAGE-BANDWIDTH=800000,RESOLUTION=640x360
+360p/playlist.m3u8
+
+`
+	if got := string(data); got != want {
+		t.Errorf("master playlist:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.Contains(string(data), "#EXT-X-VERSION:7\n") {
+		t.Errorf("master playlist does not declare version 7")
+	}
+}
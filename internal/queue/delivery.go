@@ -0,0 +1,124 @@
+package queue
+
+// Importações necessárias para o tratamento das entregas
+import (
+	"context"                    // Para o contexto do job
+	"encoding/json"              // Para decodificação de mensagens JSON
+	"errors"                     // Para identificar jobs cancelados
+	"fmt"                        // Para formatação de strings
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para mascarar segredos em URLs
+	"ms-videos/internal/metrics" // Para as métricas da fila
+	"time"                       // Para o período de tolerância no desligamento
+)
+
+// delivery é uma mensagem entregue por um broker, pendente de confirmação
+// Cada broker implementa as operações com as suas primitivas
+type delivery interface {
+	Body() []byte      // Conteúdo da mensagem (VideoMessage em JSON)
+	Redelivered() bool // Se a mensagem já foi entregue antes sem confirmação
+	RetryCount() int   // Quantas vezes a mensagem já foi re-tentada
+	Ack() error        // Confirma o processamento
+	Requeue() error    // Devolve a mensagem para a fila, para ser reentregue
+	// Fail agenda uma retentativa ou envia a mensagem para a dead-letter queue
+	Fail(logger *slog.Logger, err error)
+}
+
+// handleDelivery decodifica e processa uma entrega, confirmando ou rejeitando no final
+// O handler roda com um contexto próprio, que só é cancelado se o período de
+// tolerância do desligamento se esgotar ou se lost for cancelado; stopConsuming
+// é chamada quando o desligamento começa, para o broker parar de entregar novas mensagens
+// lost é cancelado (com o motivo como causa) quando a entrega não pode mais ser
+// confirmada, como numa queda da conexão: o broker vai reentregá-la a outro
+// consumidor, então o job é interrompido em vez de rodar em duplicidade
+func handleDelivery(ctx context.Context, d delivery, handler Handler, gracePeriod time.Duration, stopConsuming func() error, lost context.Context) {
+	var videoMsg VideoMessage                                   // Declara uma variável do tipo VideoMessage
+	if err := json.Unmarshal(d.Body(), &videoMsg); err != nil { // Deserializa mensagem
+		// Mensagens malformadas nunca vão funcionar: vão direto para a dead-letter queue
+		d.Fail(slog.With("stage", "queue"), Permanent(fmt.Errorf("failed to unmarshal message: %w", err)))
+		return
+	}
+	if err := videoMsg.Validate(); err != nil && !ValidID.MatchString(videoMsg.ID) {
+		// Sem um ID válido a mensagem não identifica um job: vai direto para a
+		// dead-letter queue, sem passar pelo handler e sem o ID no log
+		// Com ID válido, o handler recebe a mensagem para registrar a falha do
+		// job (veja Handler) e a rejeita com erro permanente
+		d.Fail(slog.With("stage", "queue"), Permanent(err))
+		return
+	}
+
+	logger := slog.With("job_id", videoMsg.ID, "stage", "queue")
+	logger.Info("Received video message", "url", logging.Redact(videoMsg.URL), "filename", videoMsg.Filename,
+		"redelivered", d.Redelivered(), "retry_count", d.RetryCount())
+	if d.Redelivered() {
+		metrics.Redeliveries.Inc()
+	}
+
+	jobCtx, cancelJob := context.WithCancelCause(context.Background())
+	defer cancelJob(nil)
+
+	// Process the message usando handler fornecido em uma goroutine,
+	// para poder acompanhar o desligamento e a conexão enquanto o job roda
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(jobCtx, videoMsg)
+	}()
+
+	// abandon interrompe o job cuja entrega se perdeu; nada é confirmado
+	abandon := func() {
+		cause := context.Cause(lost)
+		logger.Warn("Broker connection lost while processing video, canceling it: the message will be redelivered",
+			"error", cause)
+		metrics.DeliveriesLost.Inc()
+		cancelJob(fmt.Errorf("delivery lost: %w", cause))
+		<-done
+	}
+
+	var err error
+	select {
+	case err = <-done:
+	case <-lost.Done():
+		abandon()
+		return
+	case <-ctx.Done():
+		// Drenagem: para de receber novas entregas e dá tempo ao job atual
+		logger.Info("Shutdown requested, draining video", "grace_period", gracePeriod.String())
+		if cancelErr := stopConsuming(); cancelErr != nil {
+			logger.Warn("Failed to cancel consumer", "error", cancelErr)
+		}
+
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+
+		select {
+		case err = <-done:
+			logger.Info("Video finished while draining")
+		case <-lost.Done():
+			abandon()
+			return
+		case <-timer.C:
+			cancelJob(nil) // Interrompe o job (inclusive o ffmpeg)
+			<-done
+			logger.Warn("Nacking video for redelivery: shutdown grace period expired before it finished",
+				"grace_period", gracePeriod.String())
+			d.Requeue()
+			return
+		}
+	}
+
+	if errors.Is(err, ErrCanceled) {
+		// Cancelado a pedido: nada a re-tentar, a mensagem é descartada
+		logger.Info("Video was canceled, discarding message")
+		d.Ack()
+		return
+	}
+	if err != nil {
+		d.Fail(logger, err) // Agenda retentativa ou envia para a dead-letter queue
+		return
+	}
+
+	if err := d.Ack(); err != nil { // Confirmação de processamento
+		// A conexão caiu durante o job: o broker vai reentregar a mensagem
+		logger.Warn("Failed to ack video, it will be redelivered", "error", err)
+	}
+}
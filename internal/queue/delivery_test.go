@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeDelivery registra como a entrega foi finalizada
+type fakeDelivery struct {
+	body    []byte
+	outcome string
+}
+
+func (f *fakeDelivery) Body() []byte                 { return f.body }
+func (f *fakeDelivery) Redelivered() bool            { return false }
+func (f *fakeDelivery) RetryCount() int              { return 0 }
+func (f *fakeDelivery) Ack() error                   { f.outcome = "ack"; return nil }
+func (f *fakeDelivery) Requeue() error               { f.outcome = "requeue"; return nil }
+func (f *fakeDelivery) Fail(_ *slog.Logger, _ error) { f.outcome = "fail" }
+
+// Quando a conexão cai no meio do job, o job é interrompido e a entrega não é
+// finalizada: o broker já vai reentregá-la
+func TestHandleDeliveryCancelsJobWhenDeliveryIsLost(t *testing.T) {
+	body, _ := json.Marshal(VideoMessage{ID: "long", URL: "https://example.com/a.mp4", Filename: "a.mp4"})
+	d := &fakeDelivery{body: body}
+
+	lost, markLost := context.WithCancelCause(context.Background())
+	started := make(chan struct{})
+	var jobErr error
+	handler := func(ctx context.Context, msg VideoMessage) error {
+		close(started)
+		<-ctx.Done()
+		jobErr = context.Cause(ctx)
+		return ctx.Err()
+	}
+
+	go func() {
+		<-started
+		markLost(errors.New("connection closed"))
+	}()
+
+	finished := make(chan struct{})
+	go func() {
+		handleDelivery(context.Background(), d, handler, time.Minute, func() error { return nil }, lost)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleDelivery did not return after the delivery was lost")
+	}
+
+	if d.outcome != "" {
+		t.Errorf("lost delivery was finalized with %s", d.outcome)
+	}
+	if jobErr == nil || jobErr.Error() != "delivery lost: connection closed" {
+		t.Errorf("job canceled with %v", jobErr)
+	}
+}
+
+func TestHandleDeliveryAcksWhileConnected(t *testing.T) {
+	body, _ := json.Marshal(VideoMessage{ID: "short", URL: "https://example.com/a.mp4", Filename: "a.mp4"})
+	d := &fakeDelivery{body: body}
+	handleDelivery(context.Background(), d, func(context.Context, VideoMessage) error { return nil },
+		time.Minute, func() error { return nil }, context.Background())
+	if d.outcome != "ack" {
+		t.Errorf("outcome = %q, want ack", d.outcome)
+	}
+}
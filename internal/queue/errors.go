@@ -0,0 +1,59 @@
+package queue
+
+// Importações necessárias para a classificação de erros
+import (
+	"errors" // Para inspecionar a cadeia de erros
+)
+
+// ErrCanceled indica que o job foi cancelado a pedido (por exemplo, pela API)
+// Mensagens canceladas são confirmadas e descartadas, sem retentativa
+var ErrCanceled = errors.New("job canceled")
+
+// PermanentError marca um erro que não adianta tentar de novo, como uma URL que
+// responde 404 ou um arquivo corrompido
+// Mensagens que falham com esse erro vão direto para a dead-letter queue
+type PermanentError struct {
+	Err error // Erro original
+}
+
+// Error implementa a interface error
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap permite que errors.Is/errors.As enxerguem o erro original
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent embrulha err como permanente (nil continua nil)
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent indica se algum erro da cadeia foi marcado como permanente
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// Classes de erro usadas nos headers das mensagens mortas
+const (
+	ErrorClassRetryable = "retryable" // Falha transitória, pode dar certo numa nova tentativa
+	ErrorClassPermanent = "permanent" // Falha definitiva, não deve ser tentada de novo
+	ErrorClassCanceled  = "canceled"  // Job cancelado a pedido
+)
+
+// ErrorClass retorna a classe do erro
+func ErrorClass(err error) string {
+	if errors.Is(err, ErrCanceled) {
+		return ErrorClassCanceled
+	}
+	if IsPermanent(err) {
+		return ErrorClassPermanent
+	}
+	return ErrorClassRetryable
+}
@@ -0,0 +1,80 @@
+package queue
+
+// Importações necessárias para publicar eventos do ciclo de vida dos jobs
+import (
+	"context"       // Para limitar o tempo de publicação
+	"encoding/json" // Para serialização dos eventos
+	"fmt"           // Para formatação de strings
+	"time"          // Para o horário dos eventos
+)
+
+// Tipos de evento publicados durante o processamento de um vídeo
+const (
+	EventReceived    = "received"    // Job recebido da fila
+	EventDownloading = "downloading" // Download da fonte iniciado
+	EventEncoding    = "encoding"    // Progresso do encoding de uma variante
+	EventUploading   = "uploading"   // Upload dos arquivos HLS iniciado
+	EventCompleted   = "completed"   // Job concluído com sucesso
+	EventFailed      = "failed"      // Job falhou
+	EventCanceled    = "canceled"    // Job cancelado a pedido
+)
+
+// JobEvent é um evento do ciclo de vida de um job
+// É publicado no exchange de eventos com a chave de roteamento "video.<type>"
+type JobEvent struct {
+	Type            string          `json:"type"`                       // Tipo do evento (EventReceived, ...)
+	JobID           string          `json:"job_id"`                     // ID do vídeo
+	Timestamp       time.Time       `json:"timestamp"`                  // Momento do evento
+	Rendition       string          `json:"rendition,omitempty"`        // Variante (eventos de encoding)
+	Percent         float64         `json:"percent,omitempty"`          // Progresso da variante, de 0 a 100
+	OutTimeSeconds  float64         `json:"out_time_seconds,omitempty"` // Quanto da fonte já foi codificado (eventos de encoding)
+	Speed           float64         `json:"speed,omitempty"`            // Velocidade do ffmpeg em relação ao tempo real (eventos de encoding)
+	FPS             float64         `json:"fps,omitempty"`              // Quadros codificados por segundo (eventos de encoding)
+	ManifestKeys    []string        `json:"manifest_keys,omitempty"`    // Chaves das playlists (evento completed)
+	Renditions      []RenditionInfo `json:"renditions,omitempty"`       // Variantes produzidas (evento completed)
+	DurationSeconds float64         `json:"duration_seconds,omitempty"` // Duração do vídeo (evento completed)
+	Source          *SourceInfo     `json:"source,omitempty"`           // Hash e tamanho da fonte processada (evento completed)
+	ElapsedSeconds  float64         `json:"elapsed_seconds,omitempty"`  // Tempo de processamento (completed/failed/canceled)
+	Error           string          `json:"error,omitempty"`            // Mensagem de erro (evento failed)
+	ErrorClass      string          `json:"error_class,omitempty"`      // Classe do erro (evento failed)
+}
+
+// RenditionInfo descreve uma variante produzida, com os valores medidos
+type RenditionInfo struct {
+	Name             string  `json:"name"`                 // Nome da variante (ex: "720p")
+	Width            int     `json:"width"`                // Largura real
+	Height           int     `json:"height"`               // Altura real
+	FrameRate        float64 `json:"frame_rate,omitempty"` // Quadros por segundo
+	PeakBandwidth    int     `json:"peak_bandwidth"`       // Maior bitrate de segmento em bits/s
+	AverageBandwidth int     `json:"average_bandwidth"`    // Bitrate médio em bits/s
+	Codecs           string  `json:"codecs,omitempty"`     // Codecs no formato RFC 6381
+	Playlist         string  `json:"playlist"`             // Chave da playlist da variante
+}
+
+// SourceInfo identifica a fonte processada, para deduplicação e auditoria
+// Os hashes são sempre calculados, mesmo que a mensagem não traga um esperado
+type SourceInfo struct {
+	SHA256 string `json:"sha256"`        // SHA-256 do conteúdo, em hexadecimal
+	MD5    string `json:"md5,omitempty"` // MD5 do conteúdo, quando a mensagem pediu verificação por MD5
+	Size   int64  `json:"size"`          // Tamanho em bytes
+}
+
+// PublishEvent publica um evento no exchange de eventos com a chave de
+// roteamento "video.<type>" e espera a confirmação do broker
+func (p *RabbitMQPublisher) PublishEvent(ctx context.Context, ev JobEvent) error {
+	if p.exchange == "" {
+		return fmt.Errorf("no events exchange configured")
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := p.publish(ctx, p.exchange, "video."+ev.Type, ev.Timestamp, body); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", ev.Type, err)
+	}
+	return nil
+}
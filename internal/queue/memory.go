@@ -0,0 +1,342 @@
+package queue
+
+// Importações necessárias para o broker em memória
+import (
+	"context"                    // Para controle de contexto
+	"encoding/json"              // Para serialização das mensagens
+	"errors"                     // Para os erros do broker
+	"fmt"                        // Para formatação de strings
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para mascarar segredos em URLs
+	"ms-videos/internal/metrics" // Para as métricas de retentativas
+	"sync"                       // Para acesso concorrente às filas
+	"time"                       // Para os atrasos entre tentativas
+)
+
+// ErrBrokerClosed indica que o broker em memória foi fechado
+var ErrBrokerClosed = errors.New("memory broker closed")
+
+// MemoryBroker é um broker dentro do processo, sem dependências externas
+// Serve para testes de ponta a ponta e para rodar uma única instância sem
+// RabbitMQ; as mensagens não sobrevivem a uma reinicialização
+// Reproduz a semântica do RabbitMQ: confirmação manual, reentrega de mensagens
+// não confirmadas, retentativas com atraso e dead-letter queue
+type MemoryBroker struct {
+	opts   Options                 // Retentativas e período de tolerância dos consumidores
+	mu     sync.Mutex              // Protege queues e os timers de retentativa
+	queues map[string]*memoryQueue // Filas pelo nome, criadas no primeiro uso
+	timers map[*time.Timer]bool    // Retentativas agendadas, paradas no Close
+	done   chan struct{}           // Fechado pelo Close
+}
+
+// memoryQueue é uma fila FIFO de mensagens prontas para entrega
+type memoryQueue struct {
+	ready  []*memoryMessage // Mensagens aguardando um consumidor
+	notify chan struct{}    // Sinaliza consumidores parados de que há mensagens
+}
+
+// memoryMessage é uma mensagem guardada no broker
+type memoryMessage struct {
+	body        []byte            // Conteúdo (VideoMessage em JSON)
+	redelivered bool              // Já foi entregue e devolvida sem confirmação
+	retries     int               // Quantas vezes já foi re-tentada
+	headers     map[string]string // Motivo da última falha (headerError, ...)
+}
+
+// DeadLetter é uma mensagem que esgotou as tentativas ou falhou de forma permanente
+type DeadLetter struct {
+	Message    VideoMessage // Mensagem original (vazia se não era JSON válido)
+	Body       []byte       // Conteúdo original
+	Retries    int          // Quantas retentativas foram feitas
+	Error      string       // Último erro
+	ErrorClass string       // Classe do último erro
+	FailedAt   time.Time    // Momento da falha
+}
+
+// NewMemoryBroker cria um broker vazio
+// As opções de retentativa e o período de tolerância valem para todos os consumidores
+func NewMemoryBroker(opts Options) *MemoryBroker {
+	return &MemoryBroker{
+		opts:   opts.withRetryDefaults(),
+		queues: make(map[string]*memoryQueue),
+		timers: make(map[*time.Timer]bool),
+		done:   make(chan struct{}),
+	}
+}
+
+// queue retorna a fila pelo nome, criando-a se necessário; exige b.mu travado
+func (b *MemoryBroker) queue(name string) *memoryQueue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memoryQueue{notify: make(chan struct{}, 1)}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// closed indica se o broker já foi fechado
+func (b *MemoryBroker) closed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// push coloca a mensagem no fim da fila (ou no início, para reentregas) e
+// acorda um consumidor; exige b.mu travado
+func (b *MemoryBroker) push(queueName string, msg *memoryMessage, front bool) {
+	q := b.queue(queueName)
+	if front {
+		q.ready = append([]*memoryMessage{msg}, q.ready...)
+	} else {
+		q.ready = append(q.ready, msg)
+	}
+	q.signal()
+}
+
+// signal acorda um consumidor parado, sem bloquear
+func (q *memoryQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop espera a próxima mensagem da fila até o contexto ser cancelado ou o broker fechado
+func (b *MemoryBroker) pop(ctx context.Context, queueName string) (*memoryMessage, error) {
+	for {
+		b.mu.Lock()
+		if b.closed() {
+			b.mu.Unlock()
+			return nil, ErrBrokerClosed
+		}
+		q := b.queue(queueName)
+		if len(q.ready) > 0 {
+			msg := q.ready[0]
+			q.ready = q.ready[1:]
+			if len(q.ready) > 0 {
+				q.signal() // Ainda há mensagens: acorda outro consumidor
+			}
+			b.mu.Unlock()
+			return msg, nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-b.done:
+			return nil, ErrBrokerClosed
+		case <-q.notify:
+		}
+	}
+}
+
+// PublishJob coloca a mensagem de vídeo no fim da fila queueName
+func (b *MemoryBroker) PublishJob(ctx context.Context, queueName string, msg VideoMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed() {
+		return fmt.Errorf("failed to publish video %s: %w", msg.ID, ErrBrokerClosed)
+	}
+	b.push(queueName, &memoryMessage{body: body}, false)
+	return nil
+}
+
+// Len retorna quantas mensagens estão prontas na fila queueName
+func (b *MemoryBroker) Len(queueName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok := b.queues[queueName]; ok {
+		return len(q.ready)
+	}
+	return 0
+}
+
+// DeadLetters retorna as mensagens na dead-letter queue da fila queueName
+// As mensagens continuam na dead-letter queue
+func (b *MemoryBroker) DeadLetters(queueName string) []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName+".dlq"]
+	if !ok {
+		return nil
+	}
+	out := make([]DeadLetter, 0, len(q.ready))
+	for _, msg := range q.ready {
+		dl := DeadLetter{
+			Body:       msg.body,
+			Retries:    msg.retries,
+			Error:      msg.headers[headerError],
+			ErrorClass: msg.headers[headerErrorClass],
+		}
+		dl.FailedAt, _ = time.Parse(time.RFC3339, msg.headers[headerFailedAt])
+		json.Unmarshal(msg.body, &dl.Message) // Mensagens malformadas ficam com Message vazia
+		out = append(out, dl)
+	}
+	return out
+}
+
+// Consumer cria um consumidor da fila queueName
+// Vários consumidores da mesma fila dividem as mensagens entre si
+func (b *MemoryBroker) Consumer(queueName string) *MemoryConsumer {
+	return &MemoryConsumer{
+		broker:    b,
+		queueName: queueName,
+		retry:     newRetryTopology(queueName, b.opts),
+	}
+}
+
+// Close descarta as mensagens e as retentativas agendadas e para os consumidores
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed() {
+		return
+	}
+	close(b.done)
+	for t := range b.timers {
+		t.Stop()
+	}
+	b.timers = nil
+	b.queues = make(map[string]*memoryQueue)
+}
+
+// MemoryConsumer consome uma fila do MemoryBroker, uma mensagem por vez
+type MemoryConsumer struct {
+	broker    *MemoryBroker  // Broker dono da fila
+	queueName string         // Nome da fila
+	retry     *retryTopology // Filas de espera e dead-letter queue
+}
+
+// StartConsuming processa mensagens até o contexto ser cancelado ou o broker fechado
+// O desligamento segue as mesmas regras do RabbitMQConsumer
+func (c *MemoryConsumer) StartConsuming(ctx context.Context, handler Handler) error {
+	for {
+		msg, err := c.broker.pop(ctx, c.queueName)
+		if err != nil {
+			return err
+		}
+
+		handleDelivery(ctx, &memoryDelivery{c: c, msg: msg}, handler, c.broker.opts.GracePeriod, func() error {
+			return nil // Nenhuma mensagem nova é retirada enquanto o job roda
+		}, context.Background()) // Em memória, a entrega nunca se perde
+
+		if ctx.Err() != nil {
+			slog.Info("Drain complete, stopping consumer")
+			return ctx.Err()
+		}
+	}
+}
+
+// Health informa se o broker ainda está aberto
+func (c *MemoryConsumer) Health() error {
+	if c.broker.closed() {
+		return ErrBrokerClosed
+	}
+	return nil
+}
+
+// QueueDepth retorna quantas mensagens estão prontas na fila
+func (c *MemoryConsumer) QueueDepth() (int, error) {
+	return c.broker.Len(c.queueName), nil
+}
+
+// Close não faz nada: a vida das filas é controlada pelo MemoryBroker
+func (c *MemoryConsumer) Close() {}
+
+// memoryDelivery é uma mensagem retirada da fila e ainda não confirmada
+type memoryDelivery struct {
+	c       *MemoryConsumer // Consumidor que recebeu a mensagem
+	msg     *memoryMessage  // Mensagem entregue
+	settled bool            // Já foi confirmada ou devolvida
+}
+
+func (d *memoryDelivery) Body() []byte      { return d.msg.body }
+func (d *memoryDelivery) Redelivered() bool { return d.msg.redelivered }
+func (d *memoryDelivery) RetryCount() int   { return d.msg.retries }
+
+// Ack descarta a mensagem
+func (d *memoryDelivery) Ack() error {
+	if d.settled {
+		return errors.New("delivery already settled")
+	}
+	d.settled = true
+	return nil
+}
+
+// Requeue devolve a mensagem para o início da fila, marcada como reentrega
+func (d *memoryDelivery) Requeue() error {
+	if err := d.Ack(); err != nil {
+		return err
+	}
+
+	b := d.c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed() {
+		return ErrBrokerClosed
+	}
+	d.msg.redelivered = true
+	b.push(d.c.queueName, d.msg, true)
+	return nil
+}
+
+// Fail agenda a retentativa com o atraso da tentativa ou move a mensagem para
+// a dead-letter queue, como o RabbitMQConsumer
+func (d *memoryDelivery) Fail(logger *slog.Logger, procErr error) {
+	if err := d.Ack(); err != nil {
+		logger.Warn("Failed to settle video", "error", err)
+		return
+	}
+
+	attempt := d.msg.retries + 1
+	failed := &memoryMessage{
+		body:    d.msg.body,
+		retries: d.msg.retries,
+		headers: map[string]string{
+			headerError:      logging.Redact(procErr.Error()),
+			headerErrorClass: ErrorClass(procErr),
+			headerFailedAt:   time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+
+	t := d.c.retry
+	b := d.c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed() {
+		return
+	}
+
+	retry, ok := t.next(logger, attempt, procErr)
+	if !ok {
+		b.push(t.deadLetter, failed, false)
+		metrics.DeadLettered.Inc()
+		return
+	}
+
+	// A fila de espera é um timer: ao expirar, a mensagem volta para a fila principal
+	failed.retries = attempt
+	var timer *time.Timer
+	timer = time.AfterFunc(t.delays[retry], func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.closed() {
+			return
+		}
+		delete(b.timers, timer)
+		b.push(t.queueName, failed, false)
+	})
+	b.timers[timer] = true
+	metrics.Retries.Inc()
+}
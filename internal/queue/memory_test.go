@@ -0,0 +1,212 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ms-videos/internal/storage"
+)
+
+// Fluxo completo no broker em memória, com os resultados gravados num LocalBackend:
+// confirmação, retentativa com atraso, dead-letter por erro permanente, por
+// tentativas esgotadas e por mensagem inválida, e descarte de jobs cancelados
+func TestMemoryBrokerAckRetryDeadLetter(t *testing.T) {
+	const queueName = "videos"
+
+	store, err := storage.NewLocalBackend(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := NewMemoryBroker(Options{
+		MaxRetries:     2,
+		RetryBaseDelay: 10 * time.Millisecond,
+		RetryMaxDelay:  20 * time.Millisecond,
+		GracePeriod:    time.Second,
+	})
+	defer broker.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+		finished = make(chan string, 16) // IDs dos jobs confirmados (sucesso ou cancelamento)
+	)
+	handler := func(ctx context.Context, msg VideoMessage) error {
+		mu.Lock()
+		attempts[msg.ID]++
+		attempt := attempts[msg.ID]
+		mu.Unlock()
+
+		switch msg.ID {
+		case "flaky":
+			if attempt == 1 {
+				return fmt.Errorf("connection reset by peer")
+			}
+		case "broken":
+			return fmt.Errorf("ffmpeg exited with status 1")
+		case "corrupt":
+			return Permanent(fmt.Errorf("no video stream found"))
+		case "canceled":
+			finished <- msg.ID
+			return ErrCanceled
+		}
+
+		// Sucesso: grava a "saída" do job no armazenamento
+		src := filepath.Join(t.TempDir(), "master.m3u8")
+		if err := os.WriteFile(src, []byte("#EXTM3U\n"), 0644); err != nil {
+			return err
+		}
+		if err := store.UploadFile(ctx, src, msg.ID+"/master.m3u8", "application/vnd.apple.mpegurl"); err != nil {
+			return err
+		}
+		finished <- msg.ID
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consumerDone := make(chan error, 1)
+	go func() {
+		consumerDone <- broker.Consumer(queueName).StartConsuming(ctx, handler)
+	}()
+
+	for _, id := range []string{"ok", "flaky", "broken", "corrupt", "canceled", "../escape"} {
+		msg := VideoMessage{ID: id, URL: "https://example.com/" + id + ".mp4", Filename: "source.mp4"}
+		if err := broker.PublishJob(ctx, queueName, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Espera os três jobs confirmados e as três mensagens mortas
+	deadline := time.After(5 * time.Second)
+	done := make(map[string]bool)
+	for len(done) < 3 || len(broker.DeadLetters(queueName)) < 3 {
+		select {
+		case id := <-finished:
+			done[id] = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("timed out: finished %v, dead letters %+v", done, broker.DeadLetters(queueName))
+		}
+	}
+
+	mu.Lock()
+	wantAttempts := map[string]int{"ok": 1, "flaky": 2, "broken": 3, "corrupt": 1, "canceled": 1}
+	for id, want := range wantAttempts {
+		if attempts[id] != want {
+			t.Errorf("%s: %d attempts, want %d", id, attempts[id], want)
+		}
+	}
+	if attempts["../escape"] != 0 {
+		t.Errorf("invalid message reached the handler")
+	}
+	mu.Unlock()
+
+	// Mensagens mortas, com o motivo e a classe do erro
+	dead := make(map[string]DeadLetter)
+	for _, dl := range broker.DeadLetters(queueName) {
+		dead[dl.Message.ID] = dl
+	}
+	tests := []struct {
+		id      string
+		retries int
+		class   string
+	}{
+		{"broken", 2, ErrorClassRetryable},
+		{"corrupt", 0, ErrorClassPermanent},
+		{"../escape", 0, ErrorClassPermanent},
+	}
+	for _, tt := range tests {
+		dl, ok := dead[tt.id]
+		if !ok {
+			t.Errorf("%s was not dead-lettered", tt.id)
+			continue
+		}
+		if dl.Retries != tt.retries || dl.ErrorClass != tt.class || dl.Error == "" || dl.FailedAt.IsZero() {
+			t.Errorf("%s: dead letter %+v, want %d retries and class %s", tt.id, dl, tt.retries, tt.class)
+		}
+	}
+	if len(dead) != len(tests) {
+		t.Errorf("got %d dead letters, want %d: %+v", len(dead), len(tests), dead)
+	}
+	if !strings.Contains(dead["../escape"].Error, ErrInvalidMessage.Error()) {
+		t.Errorf("invalid message error = %q", dead["../escape"].Error)
+	}
+
+	// Só os jobs concluídos deixaram saída no armazenamento
+	for _, id := range []string{"ok", "flaky"} {
+		if _, err := store.Stat(ctx, id+"/master.m3u8"); err != nil {
+			t.Errorf("%s: output not stored: %v", id, err)
+		}
+	}
+	for _, id := range []string{"broken", "corrupt", "canceled"} {
+		if _, err := store.Stat(ctx, id+"/master.m3u8"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: unexpected output (err %v)", id, err)
+		}
+	}
+	if n := broker.Len(queueName); n != 0 {
+		t.Errorf("%d messages left in the queue", n)
+	}
+
+	cancel()
+	select {
+	case err := <-consumerDone:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("StartConsuming returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not stop")
+	}
+}
+
+// Um job que não termina dentro do período de tolerância volta para a fila,
+// sem ir para a dead-letter queue
+func TestMemoryBrokerRequeuesOnShutdown(t *testing.T) {
+	broker := NewMemoryBroker(Options{GracePeriod: 20 * time.Millisecond})
+	defer broker.Close()
+
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	consumerDone := make(chan error, 1)
+	go func() {
+		consumerDone <- broker.Consumer("videos").StartConsuming(ctx, func(jobCtx context.Context, msg VideoMessage) error {
+			close(started)
+			<-jobCtx.Done() // Só termina quando o período de tolerância se esgota
+			return jobCtx.Err()
+		})
+	}()
+
+	msg := VideoMessage{ID: "slow", URL: "https://example.com/slow.mp4", Filename: "slow.mp4"}
+	if err := broker.PublishJob(context.Background(), "videos", msg); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+	<-consumerDone
+
+	if n := broker.Len("videos"); n != 1 {
+		t.Fatalf("%d messages in the queue, want the interrupted job back", n)
+	}
+	if len(broker.DeadLetters("videos")) != 0 {
+		t.Errorf("interrupted job was dead-lettered")
+	}
+
+	// O próximo consumidor recebe a mesma mensagem
+	var redelivered bool
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	broker.Consumer("videos").StartConsuming(ctx, func(jobCtx context.Context, got VideoMessage) error {
+		redelivered = got.ID == msg.ID
+		cancel()
+		return nil
+	})
+	if !redelivered {
+		t.Errorf("interrupted job was not redelivered")
+	}
+}
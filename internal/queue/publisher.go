@@ -0,0 +1,243 @@
+package queue
+
+// Importações necessárias para publicar mensagens no RabbitMQ
+import (
+	"context"       // Para limitar o tempo de publicação
+	"encoding/json" // Para serialização das mensagens
+	"errors"        // Para o erro de publisher fechado
+	"fmt"           // Para formatação de strings
+	"net"           // Para a conexão TCP com o RabbitMQ
+	"sync"          // Para proteger a conexão compartilhada
+	"time"          // Para o horário das mensagens e o prazo de conexão
+
+	amqp "github.com/rabbitmq/amqp091-go" // Cliente RabbitMQ
+)
+
+// dialTimeout limita a conexão TCP e o handshake AMQP quando o contexto não tem prazo menor
+const dialTimeout = 30 * time.Second
+
+// errPublisherClosed é retornado pelas publicações feitas depois de Close
+var errPublisherClosed = errors.New("publisher is closed")
+
+// RabbitMQPublisher publica eventos do ciclo de vida num exchange do tipo topic
+// e novos jobs na fila de vídeos
+// Usa publisher confirms: uma publicação só é considerada feita quando o broker confirma
+// O canal é compartilhado pelas publicações; o mutex só protege a troca da
+// conexão, nunca é mantido durante a publicação ou a reconexão
+type RabbitMQPublisher struct {
+	amqpURL  string           // URL de conexão, usada também nas reconexões
+	exchange string           // Exchange onde os eventos são publicados ("" = sem eventos)
+	mu       sync.Mutex       // Protege os campos abaixo
+	conn     *amqp.Connection // Conexão própria, separada da do consumidor
+	ch       *amqp.Channel    // Canal em modo confirm
+	dialing  chan struct{}    // Fechado quando a reconexão em andamento termina (nil = nenhuma)
+	closed   bool             // Close foi chamado
+}
+
+// NewRabbitMQPublisher conecta ao RabbitMQ e declara o exchange de eventos
+// Com exchange vazio, o publisher só publica jobs
+func NewRabbitMQPublisher(amqpURL, exchange string) (*RabbitMQPublisher, error) {
+	p := &RabbitMQPublisher{
+		amqpURL:  amqpURL,
+		exchange: exchange,
+	}
+	conn, ch, err := p.connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	p.conn, p.ch = conn, ch
+	return p, nil
+}
+
+// connect abre conexão e canal, declara o exchange e habilita o modo confirm
+// Não usa p.mu: quem chama guarda a conexão retornada
+func (p *RabbitMQPublisher) connect(ctx context.Context) (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := dial(ctx, p.amqpURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+
+	if p.exchange != "" {
+		err = ch.ExchangeDeclare(
+			p.exchange, // Nome do exchange
+			"topic",    // Tipo: permite assinar por padrão (ex: "video.completed")
+			true,       // Durável
+			false,      // Não deletar quando não usado
+			false,      // Não interno
+			false,      // Sem espera
+			nil,        // Sem argumentos adicionais
+		)
+		if err != nil {
+			ch.Close()
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to declare exchange %s: %w", p.exchange, err)
+		}
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return conn, ch, nil
+}
+
+// dial conecta ao RabbitMQ respeitando ctx: o prazo do ctx (ou dialTimeout, se
+// menor) vale para a conexão TCP e para o handshake, e cancelar ctx interrompe ambos
+func dial(ctx context.Context, amqpURL string) (*amqp.Connection, error) {
+	deadline := time.Now().Add(dialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	var stop func() bool // Desfaz o vínculo entre ctx e a conexão TCP
+	conn, err := amqp.DialConfig(amqpURL, amqp.Config{
+		Heartbeat: 10 * time.Second, // Os mesmos padrões de amqp.Dial
+		Locale:    "en_US",
+		Dial: func(network, addr string) (net.Conn, error) {
+			dialer := net.Dialer{Deadline: deadline}
+			c, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			// O prazo vale também para o handshake; a biblioteca o remove ao concluir
+			if err := c.SetDeadline(deadline); err != nil {
+				c.Close()
+				return nil, err
+			}
+			stop = context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+			return c, nil
+		},
+	})
+	if stop != nil && !stop() {
+		// ctx foi cancelado durante o handshake: a conexão pode ter ficado com prazo vencido
+		if err == nil {
+			conn.Close()
+		}
+		return nil, context.Cause(ctx)
+	}
+	return conn, err
+}
+
+// channel retorna o canal de publicação, reconectando se a conexão tiver caído
+// Só uma reconexão acontece por vez; as demais publicações esperam por ela
+// enquanto o próprio ctx permitir
+func (p *RabbitMQPublisher) channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errPublisherClosed
+		}
+		if p.conn != nil && !p.conn.IsClosed() && p.ch != nil && !p.ch.IsClosed() {
+			ch := p.ch
+			p.mu.Unlock()
+			return ch, nil
+		}
+		if wait := p.dialing; wait != nil {
+			p.mu.Unlock()
+			select {
+			case <-wait:
+				continue // Usa a conexão nova ou tenta de novo, se a reconexão falhou
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		p.closeLocked()
+		done := make(chan struct{})
+		p.dialing = done
+		p.mu.Unlock()
+
+		conn, ch, err := p.connect(ctx)
+
+		p.mu.Lock()
+		p.dialing = nil
+		close(done)
+		if err == nil && p.closed {
+			ch.Close()
+			conn.Close()
+			err = errPublisherClosed
+		}
+		if err == nil {
+			p.conn, p.ch = conn, ch
+		}
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return ch, nil
+	}
+}
+
+// PublishJob publica uma mensagem de vídeo na fila queueName (pelo exchange
+// padrão) e espera a confirmação do broker
+func (p *RabbitMQPublisher) PublishJob(ctx context.Context, queueName string, msg VideoMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	if err := p.publish(ctx, "", queueName, time.Now().UTC(), body); err != nil {
+		return fmt.Errorf("failed to publish video %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// publish envia uma mensagem persistente e espera a confirmação do broker
+// Se a conexão tiver caído, reconecta uma vez antes de desistir
+func (p *RabbitMQPublisher) publish(ctx context.Context, exchange, key string, timestamp time.Time, body []byte) error {
+	ch, err := p.channel(ctx)
+	if err != nil {
+		return err
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		exchange, // Exchange de destino ("" = exchange padrão)
+		key,      // Chave de roteamento
+		false,    // Obrigatório
+		false,    // Imediato
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    timestamp,
+			Body:         body,
+		})
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for confirmation: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("broker rejected the message")
+	}
+	return nil
+}
+
+// Close encerra o canal e a conexão do publisher
+// Publicações posteriores falham com errPublisherClosed
+func (p *RabbitMQPublisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.closeLocked()
+}
+
+// closeLocked fecha canal e conexão; exige p.mu travado
+func (p *RabbitMQPublisher) closeLocked() {
+	if p.ch != nil {
+		p.ch.Close()
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.ch = nil, nil
+}
@@ -0,0 +1,38 @@
+package queue
+
+// Importações necessárias para as interfaces da fila
+import (
+	"context" // Para controle de contexto
+)
+
+// Brokers disponíveis
+const (
+	BackendRabbitMQ = "rabbitmq" // RabbitMQ, para produção e múltiplas instâncias
+	BackendMemory   = "memory"   // Em memória, para testes e modo de instância única
+)
+
+// Consumer entrega as mensagens de uma fila a um Handler
+// Implementado por RabbitMQConsumer e MemoryConsumer, com a mesma semântica:
+// a mensagem só é confirmada depois do handler; falhas transitórias são
+// re-tentadas com atraso crescente e falhas permanentes vão para a dead-letter
+// queue; no desligamento, o job em andamento tem o período de tolerância para
+// terminar antes de voltar para a fila
+type Consumer interface {
+	// StartConsuming processa mensagens até o contexto ser cancelado
+	StartConsuming(ctx context.Context, handler Handler) error
+	// Health informa se o consumidor consegue receber mensagens
+	Health() error
+	// QueueDepth retorna quantas mensagens estão prontas na fila
+	QueueDepth() (int, error)
+	// Close libera a conexão com o broker
+	Close()
+}
+
+// Publisher publica jobs numa fila
+// Implementado por RabbitMQPublisher e MemoryBroker
+type Publisher interface {
+	// PublishJob publica a mensagem e só retorna depois de o broker aceitá-la
+	PublishJob(ctx context.Context, queueName string, msg VideoMessage) error
+	// Close libera a conexão com o broker
+	Close()
+}
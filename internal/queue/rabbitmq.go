@@ -1,13 +1,15 @@
 // Package queue contém a implementação para consumir mensagens de uma fila RabbitMQ
+// ou de um broker em memória (veja Consumer e Publisher)
 // As mensagens contêm informações sobre vídeos a serem processados
 package queue
 
 // Importações necessárias para consumir mensagens da fila
 import (
-	"context"       // Para gerenciar contexto
-	"encoding/json" // Para decodificação de mensagens JSON
-	"fmt"           // Para formatação de strings
-	"log"           // Para logging
+	"context"  // Para gerenciar contexto
+	"fmt"      // Para formatação de strings
+	"log/slog" // Para logging estruturado
+	"sync"     // Para proteger a conexão durante reconexões
+	"time"     // Para o período de tolerância no desligamento
 
 	amqp "github.com/rabbitmq/amqp091-go" // Cliente RabbitMQ
 )
@@ -15,45 +17,166 @@ import (
 // VideoMessage representa a estrutura da mensagem contendo dados de vídeos
 // Tags JSON especificam como os campos são mapeados de/para JSON
 type VideoMessage struct {
-	ID       string `json:"id"`       // Identificador do vídeo
-	URL      string `json:"url"`      // URL de onde baixar o vídeo
-	Filename string `json:"filename"` // Nome do arquivo de vídeo
+	ID       string `json:"id"`                // Identificador do vídeo
+	URL      string `json:"url"`               // URL de onde baixar o vídeo
+	Filename string `json:"filename"`          // Nome do arquivo de vídeo
+	Profile  string `json:"profile,omitempty"` // Perfil de encoding (vazio = perfil padrão)
+	// Auth são credenciais opcionais para baixar a fonte por http ou https
+	Auth *SourceAuth `json:"auth,omitempty"`
+	// Checksum é o hash esperado da fonte, "sha256:<hex>" ou "md5:<hex>" (opcional)
+	Checksum string `json:"checksum,omitempty"`
+	// Size é o tamanho esperado da fonte em bytes (0 = não verificado)
+	Size int64 `json:"size,omitempty"`
 }
 
+// SourceAuth são as credenciais de uma fonte HTTP, enviadas apenas para o host
+// da URL (nunca para o destino de um redirecionamento para outro host ou de
+// https para http)
+// Viajam na mensagem: prefira tokens de curta duração
+type SourceAuth struct {
+	Headers  map[string]string `json:"headers,omitempty"`  // Headers da requisição (ex: Authorization)
+	Username string            `json:"username,omitempty"` // Usuário para autenticação basic
+	Password string            `json:"password,omitempty"` // Senha para autenticação basic
+}
+
+// Handler processa uma mensagem de vídeo
+// O contexto é cancelado quando o job precisa ser interrompido (por exemplo, quando
+// o período de tolerância do desligamento se esgota)
+// A mensagem sempre tem um ID válido, mas os demais campos podem ser inválidos:
+// o handler deve chamar VideoMessage.Validate e falhar com erro permanente
+// (processor.VideoProcessor.ProcessVideo faz isso, registrando a falha do job)
+type Handler func(ctx context.Context, msg VideoMessage) error
+
 // RabbitMQConsumer é responsável por conectar e consumir mensagens de uma fila RabbitMQ
+// Se a conexão cair, o consumidor reconecta sozinho, re-declara as filas e
+// volta a consumir
 type RabbitMQConsumer struct {
-	conn      *amqp.Connection // Conexão com RabbitMQ
-	ch        *amqp.Channel    // Canal de comunicação com RabbitMQ
-	queueName string           // Nome da fila
+	amqpURL     string           // URL de conexão, usada também nas reconexões
+	mu          sync.RWMutex     // Protege conn, ch e lastErr durante reconexões
+	conn        *amqp.Connection // Conexão com RabbitMQ
+	ch          *amqp.Channel    // Canal de comunicação com RabbitMQ
+	lastErr     error            // Motivo da última queda (nil enquanto conectado)
+	queueName   string           // Nome da fila
+	consumerTag string           // Identificador do consumidor no canal
+	gracePeriod time.Duration    // Tempo para o job em andamento terminar no desligamento
+	retry       *retryTopology   // Filas de espera e dead-letter queue
+	reconnect   backoff          // Intervalos entre tentativas de reconexão
+	inspectMu   sync.Mutex       // Protege inspectCh
+	inspectCh   *amqp.Channel    // Canal reaproveitado pelas consultas de QueueDepth
+}
+
+// Options reúne as configurações opcionais do consumidor
+type Options struct {
+	GracePeriod       time.Duration // Tempo para o job em andamento terminar no desligamento
+	MaxRetries        int           // Tentativas extras antes da dead-letter queue
+	RetryBaseDelay    time.Duration // Atraso antes da 1ª retentativa (dobra a cada tentativa)
+	RetryMaxDelay     time.Duration // Atraso máximo entre tentativas
+	ReconnectMinDelay time.Duration // Espera antes da 1ª tentativa de reconexão
+	ReconnectMaxDelay time.Duration // Espera máxima entre tentativas de reconexão
+}
+
+// withRetryDefaults preenche os atrasos de retentativa não configurados
+func (o Options) withRetryDefaults() Options {
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = 30 * time.Second
+	}
+	if o.RetryMaxDelay < o.RetryBaseDelay {
+		o.RetryMaxDelay = o.RetryBaseDelay
+	}
+	return o
+}
+
+// backoff calcula esperas exponenciais entre min e max
+type backoff struct {
+	min time.Duration // Primeira espera
+	max time.Duration // Espera máxima
+}
+
+// delay retorna a espera antes da tentativa attempt (começando em 0)
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.min
+	for i := 0; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	return d
 }
 
 // NewRabbitMQConsumer cria um novo consumidor RabbitMQ
 // Configura a conexão, abre o canal e declara a fila
-func NewRabbitMQConsumer(amqpURL, queueName string) (*RabbitMQConsumer, error) {
-	conn, err := amqp.Dial(amqpURL) // Estabelece conexão com RabbitMQ
+func NewRabbitMQConsumer(amqpURL, queueName string, opts Options) (*RabbitMQConsumer, error) {
+	opts = opts.withRetryDefaults()
+	if opts.ReconnectMinDelay <= 0 {
+		opts.ReconnectMinDelay = time.Second
+	}
+	if opts.ReconnectMaxDelay <= 0 {
+		opts.ReconnectMaxDelay = 30 * time.Second
+	}
+	if opts.ReconnectMaxDelay < opts.ReconnectMinDelay {
+		opts.ReconnectMaxDelay = opts.ReconnectMinDelay
+	}
+
+	c := &RabbitMQConsumer{
+		amqpURL:     amqpURL,
+		queueName:   queueName,
+		consumerTag: fmt.Sprintf("ms-videos-%d", time.Now().UnixNano()),
+		gracePeriod: opts.GracePeriod,
+		retry:       newRetryTopology(queueName, opts),
+		reconnect:   backoff{min: opts.ReconnectMinDelay, max: opts.ReconnectMaxDelay},
+	}
+
+	// A primeira conexão precisa funcionar: sem ela a configuração provavelmente está errada
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil // Retorna o consumidor configurado
+}
+
+// connect abre conexão e canal e declara toda a topologia (fila principal,
+// filas de espera e dead-letter queue)
+// É usado tanto na criação do consumidor quanto nas reconexões
+func (c *RabbitMQConsumer) connect() error {
+	conn, err := amqp.Dial(c.amqpURL) // Estabelece conexão com RabbitMQ
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
 	ch, err := conn.Channel() // Abre um canal na conexão
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to open a channel: %w", err)
+		return fmt.Errorf("failed to open a channel: %w", err)
 	}
 
 	// Declara a fila, garantindo que ela existe
 	_, err = ch.QueueDeclare(
-		queueName, // Nome da fila
-		true,      // Durável (sobrevive a reinicializações)
-		false,     // Não deletar quando não usável
-		false,     // Não exclusiva (pode ser usada por outros consumidores)
-		false,     // Sem espera
-		nil,       // Sem argumentos adicionais
+		c.queueName, // Nome da fila
+		true,        // Durável (sobrevive a reinicializações)
+		false,       // Não deletar quando não usável
+		false,       // Não exclusiva (pode ser usada por outros consumidores)
+		false,       // Sem espera
+		nil,         // Sem argumentos adicionais
 	)
 	if err != nil {
 		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to declare a queue: %w", err)
+		return fmt.Errorf("failed to declare a queue: %w", err)
+	}
+
+	// Declara a dead-letter queue e as filas de espera das retentativas
+	if err := c.retry.declare(ch); err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+
+	// Modo confirm: as cópias enviadas para retentativa/dead-letter são confirmadas
+	// pelo broker antes de confirmarmos a mensagem original
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
 	}
 
 	// Define QoS para processar uma mensagem por vez
@@ -65,27 +188,115 @@ func NewRabbitMQConsumer(amqpURL, queueName string) (*RabbitMQConsumer, error) {
 	if err != nil {
 		ch.Close()
 		conn.Close()
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
+		return fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	return &RabbitMQConsumer{ // Retorna o consumidor configurado
-		conn:      conn,
-		ch:        ch,
-		queueName: queueName,
-	}, nil
+	c.mu.Lock()
+	c.conn, c.ch, c.lastErr = conn, ch, nil
+	c.mu.Unlock()
+	return nil
+}
+
+// Health informa se o consumidor está conectado
+// Retorna o motivo da queda enquanto estiver desconectado ou reconectando
+func (c *RabbitMQConsumer) Health() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.lastErr != nil {
+		return fmt.Errorf("disconnected from RabbitMQ: %w", c.lastErr)
+	}
+	if c.conn == nil || c.conn.IsClosed() {
+		return fmt.Errorf("RabbitMQ connection is closed")
+	}
+	if c.ch == nil || c.ch.IsClosed() {
+		return fmt.Errorf("RabbitMQ channel is closed")
+	}
+	return nil
 }
 
 // StartConsuming começa a consumir mensagens e processa cada uma usando o handler fornecido
-// Continua consumindo até que o contexto seja cancelado ou erro ocorra
-func (c *RabbitMQConsumer) StartConsuming(ctx context.Context, handler func(VideoMessage) error) error {
-	msgs, err := c.ch.Consume( // Inicia o consumo de mensagens
-		c.queueName, // Nome da fila
-		"",          // Nome do consumidor (gerado automaticamente se vazio)
-		false,       // Auto-acknowledge desabilitado (manter controle manual)
-		false,       // Exclusivo (apenas um consumidor)
-		false,       // No local (não compartilhar entre máquinas)
-		false,       // Sem espera
-		nil,         // Sem argumentos adicionais
+// Continua consumindo até que o contexto seja cancelado; quedas de conexão
+// disparam reconexões com backoff exponencial
+// Ao cancelar o contexto, o consumidor para de receber entregas e espera o job em
+// andamento terminar dentro do período de tolerância antes de fechar a conexão
+func (c *RabbitMQConsumer) StartConsuming(ctx context.Context, handler Handler) error {
+	for {
+		err := c.consume(ctx, handler)
+		if ctx.Err() != nil {
+			c.Close()
+			return ctx.Err()
+		}
+
+		// A conexão caiu: marca o estado degradado e tenta reconectar
+		slog.Warn("Lost connection to RabbitMQ", "error", err)
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+		c.Close()
+
+		if err := c.reconnectLoop(ctx); err != nil {
+			return err
+		}
+		slog.Info("Reconnected to RabbitMQ, resuming consumption")
+	}
+}
+
+// reconnectLoop tenta reconectar até conseguir ou até o contexto ser cancelado
+func (c *RabbitMQConsumer) reconnectLoop(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		delay := c.reconnect.delay(attempt)
+		slog.Info("Reconnecting to RabbitMQ", "delay", delay.String(), "attempt", attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		err := c.connect()
+		if err == nil {
+			return nil
+		}
+		slog.Warn("Reconnection attempt failed", "attempt", attempt+1, "error", err)
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+	}
+}
+
+// consume processa entregas até o contexto ser cancelado (retorna nil) ou a
+// conexão/canal cair (retorna o motivo)
+// Usa apenas o canal lido aqui, sob c.mu: c.ch é trocado pelas reconexões
+func (c *RabbitMQConsumer) consume(ctx context.Context, handler Handler) error {
+	c.mu.RLock()
+	conn, ch := c.conn, c.ch
+	c.mu.RUnlock()
+
+	// Avisos de fechamento da conexão e do canal, acompanhados numa goroutine
+	// para interromper também o job em andamento (veja handleDelivery)
+	// A goroutine termina quando a conexão é fechada, inclusive por Close
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	lost, markLost := context.WithCancelCause(context.Background())
+	defer markLost(nil)
+	go func() {
+		select {
+		case amqpErr := <-connClosed:
+			markLost(closeReason("connection", amqpErr))
+		case amqpErr := <-chClosed:
+			markLost(closeReason("channel", amqpErr))
+		}
+	}()
+
+	msgs, err := ch.Consume( // Inicia o consumo de mensagens
+		c.queueName,   // Nome da fila
+		c.consumerTag, // Nome do consumidor (usado para cancelar no desligamento)
+		false,         // Auto-acknowledge desabilitado (manter controle manual)
+		false,         // Exclusivo (apenas um consumidor)
+		false,         // No local (não compartilhar entre máquinas)
+		false,         // Sem espera
+		nil,           // Sem argumentos adicionais
 	)
 	if err != nil {
 		return fmt.Errorf("failed to register a consumer: %w", err)
@@ -94,47 +305,109 @@ func (c *RabbitMQConsumer) StartConsuming(ctx context.Context, handler func(Vide
 	for { // Loop contínuo para processar mensagens conforme chegam
 		select {
 		case <-ctx.Done(): // Quando o contexto for cancelado
-			log.Println("Context cancelled, stopping consumer")
-			c.Close()
-			return ctx.Err()
+			slog.Info("Context cancelled, stopping consumer")
+			return nil
+		case <-lost.Done():
+			return context.Cause(lost)
 		case d, ok := <-msgs: // Mensagem recebida da fila
 			if !ok { // Se o canal de mensagens está fechado
-				log.Println("Messages channel closed")
-				return nil
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("deliveries channel closed")
 			}
 
-			var videoMsg VideoMessage                                 // Declara uma variável do tipo VideoMessage
-			if err := json.Unmarshal(d.Body, &videoMsg); err != nil { // Deserializa mensagem
-				log.Printf("Failed to unmarshal message: %v", err)
-				d.Nack(false, false) // Não re-enfileira mensagens malformadas
+			// Uma entrega pode chegar junto com o cancelamento; ela ainda não
+			// começou, então volta para a fila sem ser processada
+			if ctx.Err() != nil {
+				d.Nack(false, true)
 				continue
 			}
 
-			log.Printf("Received video message: ID=%s, URL=%s, Filename=%s", videoMsg.ID, videoMsg.URL, videoMsg.Filename)
+			handleDelivery(ctx, rabbitDelivery{c: c, ch: ch, d: d}, handler, c.gracePeriod, func() error {
+				return ch.Cancel(c.consumerTag, false)
+			}, lost)
 
-			// Process the message usando handler fornecido
-			if err := handler(videoMsg); err != nil {
-				log.Printf("Failed to process video %s: %v", videoMsg.ID, err)
-				d.Nack(false, true) // Re-enfileira em caso de erro de processamento
-				continue
+			// Após uma drenagem o canal de entregas é fechado pelo Cancel;
+			// o desligamento tem prioridade sobre esse fechamento
+			if ctx.Err() != nil {
+				slog.Info("Drain complete, stopping consumer")
+				return nil
 			}
+		}
+	}
+}
+
+// closeReason descreve por que a conexão ou o canal foi fechado
+func closeReason(what string, amqpErr *amqp.Error) error {
+	if amqpErr == nil {
+		return fmt.Errorf("%s closed", what)
+	}
+	return fmt.Errorf("%s closed: %w", what, amqpErr)
+}
+
+// rabbitDelivery adapta uma entrega do RabbitMQ para handleDelivery
+type rabbitDelivery struct {
+	c  *RabbitMQConsumer // Consumidor que recebeu a entrega
+	ch *amqp.Channel     // Canal por onde a entrega chegou
+	d  amqp.Delivery     // Entrega original
+}
+
+func (r rabbitDelivery) Body() []byte      { return r.d.Body }
+func (r rabbitDelivery) Redelivered() bool { return r.d.Redelivered }
+func (r rabbitDelivery) RetryCount() int   { return retryCount(r.d) }
+func (r rabbitDelivery) Ack() error        { return r.d.Ack(false) }
+func (r rabbitDelivery) Requeue() error    { return r.d.Nack(false, true) }
+
+func (r rabbitDelivery) Fail(logger *slog.Logger, err error) {
+	r.c.handleFailure(r.ch, r.d, logger, err)
+}
 
-			log.Printf("Successfully processed video %s", videoMsg.ID)
-			d.Ack(false) // Confirmação de processamento
+// QueueDepth retorna quantas mensagens estão prontas na fila principal
+// Usa um canal próprio, separado do consumo (a declaração passiva fecha o canal
+// se a fila não existir), aberto na primeira consulta e reaproveitado nas
+// seguintes; só é reaberto depois que fecha, como numa reconexão
+func (c *RabbitMQConsumer) QueueDepth() (int, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return 0, fmt.Errorf("RabbitMQ connection is closed")
+	}
+
+	c.inspectMu.Lock()
+	defer c.inspectMu.Unlock()
+	if c.inspectCh == nil || c.inspectCh.IsClosed() {
+		ch, err := conn.Channel()
+		if err != nil {
+			return 0, fmt.Errorf("failed to open a channel: %w", err)
 		}
+		c.inspectCh = ch
+	}
+
+	q, err := c.inspectCh.QueueDeclarePassive(c.queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect queue %s: %w", c.queueName, err)
 	}
+	return q.Messages, nil
 }
 
-// Close encerra a conexão e o canal com RabbitMQ
+// Close encerra a conexão e os canais com RabbitMQ
 func (c *RabbitMQConsumer) Close() {
+	c.inspectMu.Lock()
+	if c.inspectCh != nil {
+		c.inspectCh.Close()
+		c.inspectCh = nil
+	}
+	c.inspectMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.ch != nil {
 		c.ch.Close() // Fecha o canal se aberto
 	}
 	if c.conn != nil {
 		c.conn.Close() // Fecha a conexão se aberta
 	}
-
-	if c.conn != nil {
-		c.conn.Close()
-	}
 }
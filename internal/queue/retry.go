@@ -0,0 +1,183 @@
+package queue
+
+// Importações necessárias para retentativas e dead-lettering
+import (
+	"context"                    // Para limitar o tempo de publicação
+	"fmt"                        // Para formatação de strings
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para mascarar segredos em URLs
+	"ms-videos/internal/metrics" // Para as métricas de retentativas
+	"time"                       // Para os atrasos entre tentativas
+
+	amqp "github.com/rabbitmq/amqp091-go" // Cliente RabbitMQ
+)
+
+// Headers usados para acompanhar as tentativas de uma mensagem
+const (
+	headerRetryCount = "x-retry-count" // Quantas vezes a mensagem já foi re-tentada
+	headerError      = "x-error"       // Último erro de processamento
+	headerErrorClass = "x-error-class" // Classe do último erro (retryable/permanent)
+	headerFailedAt   = "x-failed-at"   // Momento da última falha
+)
+
+// retryTopology descreve as filas auxiliares de uma fila principal
+// Cada tentativa tem sua própria fila de espera com TTL fixo; ao expirar, a
+// mensagem volta para a fila principal através do dead-letter da fila de espera
+// Filas separadas evitam que uma mensagem com TTL longo bloqueie as de TTL curto
+type retryTopology struct {
+	queueName   string          // Fila principal
+	deadLetter  string          // Fila das mensagens que esgotaram as tentativas
+	retryQueues []string        // Fila de espera de cada tentativa (índice 0 = 1ª)
+	delays      []time.Duration // Atraso de cada tentativa
+}
+
+// newRetryTopology calcula os nomes e atrasos das filas auxiliares
+// O atraso dobra a cada tentativa, limitado por maxDelay; o atraso faz parte do
+// nome da fila para que mudar a configuração não conflite com filas já declaradas
+func newRetryTopology(queueName string, opts Options) *retryTopology {
+	t := &retryTopology{
+		queueName:  queueName,
+		deadLetter: queueName + ".dlq",
+	}
+
+	// O TTL das filas é em milissegundos
+	delay := max(opts.RetryBaseDelay.Round(time.Millisecond), time.Millisecond)
+	for i := 0; i < opts.MaxRetries; i++ {
+		if delay > opts.RetryMaxDelay {
+			delay = opts.RetryMaxDelay
+		}
+		t.delays = append(t.delays, delay)
+		t.retryQueues = append(t.retryQueues, retryQueueName(queueName, delay))
+		delay *= 2
+	}
+	return t
+}
+
+// retryQueueName monta o nome da fila de espera de um atraso: "<fila>.retry.30s",
+// ou em milissegundos quando o atraso não é um número inteiro de segundos
+// ("<fila>.retry.1500ms"), para que atrasos diferentes nunca dividam o mesmo nome
+// Redeclarar uma fila com outro x-message-ttl falha com PRECONDITION_FAILED
+func retryQueueName(queueName string, delay time.Duration) string {
+	if delay%time.Second == 0 {
+		return fmt.Sprintf("%s.retry.%ds", queueName, delay/time.Second)
+	}
+	return fmt.Sprintf("%s.retry.%dms", queueName, delay.Milliseconds())
+}
+
+// declare cria a dead-letter queue e as filas de espera no broker
+func (t *retryTopology) declare(ch *amqp.Channel) error {
+	_, err := ch.QueueDeclare(
+		t.deadLetter, // Nome da fila
+		true,         // Durável (sobrevive a reinicializações)
+		false,        // Não deletar quando não usável
+		false,        // Não exclusiva
+		false,        // Sem espera
+		nil,          // Sem argumentos adicionais
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+
+	for i, name := range t.retryQueues {
+		_, err := ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+			"x-message-ttl":             t.delays[i].Milliseconds(), // Tempo de espera antes de voltar
+			"x-dead-letter-exchange":    "",                         // Exchange padrão...
+			"x-dead-letter-routing-key": t.queueName,                // ...de volta para a fila principal
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// next decide o destino da tentativa attempt (começando em 1) que falhou com procErr
+// Retorna o índice da fila de espera da retentativa, ou ok=false quando a
+// mensagem deve ir para a dead-letter queue (erro permanente ou tentativas esgotadas)
+func (t *retryTopology) next(logger *slog.Logger, attempt int, procErr error) (retry int, ok bool) {
+	if IsPermanent(procErr) {
+		logger.Error("Video failed with a permanent error, dead-lettering", "target", t.deadLetter, "error", procErr)
+		return 0, false
+	}
+	if attempt > len(t.retryQueues) {
+		logger.Error("Video failed after all retries, dead-lettering", "retries", attempt-1, "target", t.deadLetter, "error", procErr)
+		return 0, false
+	}
+	logger.Warn("Video failed, scheduling retry", "attempt", attempt, "max_attempts", len(t.retryQueues)+1,
+		"delay", t.delays[attempt-1].String(), "error", procErr)
+	return attempt - 1, true
+}
+
+// retryCount lê quantas vezes a mensagem já foi re-tentada
+func retryCount(d amqp.Delivery) int {
+	switch v := d.Headers[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// handleFailure decide o destino de uma mensagem que falhou:
+// erros permanentes e mensagens sem tentativas restantes vão para a dead-letter
+// queue; as demais vão para a fila de espera da próxima tentativa
+// A mensagem original só é confirmada depois que a cópia foi publicada no canal
+// ch, o mesmo da entrega
+func (c *RabbitMQConsumer) handleFailure(ch *amqp.Channel, d amqp.Delivery, logger *slog.Logger, procErr error) {
+	attempt := retryCount(d) + 1
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerError] = logging.Redact(procErr.Error())
+	headers[headerErrorClass] = ErrorClass(procErr)
+	headers[headerFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	target := c.retry.deadLetter
+	if retry, ok := c.retry.next(logger, attempt, procErr); ok {
+		target = c.retry.retryQueues[retry]
+		headers[headerRetryCount] = int32(attempt)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// O canal está em modo confirm: só confirmamos a original depois que o
+	// broker confirmou a cópia
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		"",     // Exchange padrão
+		target, // Chave de roteamento (nome da fila)
+		false,  // Obrigatório
+		false,  // Imediato
+		amqp.Publishing{
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			Headers:      headers,
+			Body:         d.Body,
+		})
+	if err == nil {
+		var acked bool
+		acked, err = confirm.WaitContext(ctx)
+		if err == nil && !acked {
+			err = fmt.Errorf("broker rejected the message")
+		}
+	}
+	if err != nil {
+		// Sem a cópia publicada, a única forma de não perder a mensagem é re-enfileirar
+		logger.Error("Failed to publish video for retry, requeueing", "target", target, "error", err)
+		d.Nack(false, true)
+		return
+	}
+
+	if target == c.retry.deadLetter {
+		metrics.DeadLettered.Inc()
+	} else {
+		metrics.Retries.Inc()
+	}
+	d.Ack(false)
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRetryTopology(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		queues []string
+	}{
+		{
+			name:   "doubling up to the maximum",
+			opts:   Options{MaxRetries: 5, RetryBaseDelay: 30 * time.Second, RetryMaxDelay: 2 * time.Minute},
+			queues: []string{"videos.retry.30s", "videos.retry.60s", "videos.retry.120s", "videos.retry.120s", "videos.retry.120s"},
+		},
+		{
+			// Atrasos que não são segundos inteiros vão em milissegundos, para
+			// que dois atrasos diferentes nunca dividam o nome (e o TTL) de uma fila
+			name:   "sub-second delays",
+			opts:   Options{MaxRetries: 4, RetryBaseDelay: 250 * time.Millisecond, RetryMaxDelay: 10 * time.Second},
+			queues: []string{"videos.retry.250ms", "videos.retry.500ms", "videos.retry.1s", "videos.retry.2s"},
+		},
+		{
+			name:   "fractional seconds",
+			opts:   Options{MaxRetries: 2, RetryBaseDelay: 1500 * time.Millisecond, RetryMaxDelay: time.Minute},
+			queues: []string{"videos.retry.1500ms", "videos.retry.3s"},
+		},
+		{
+			// O TTL é em milissegundos: atrasos menores são arredondados para 1ms
+			name:   "below one millisecond",
+			opts:   Options{MaxRetries: 1, RetryBaseDelay: time.Microsecond, RetryMaxDelay: time.Second},
+			queues: []string{"videos.retry.1ms"},
+		},
+		{
+			name: "no retries",
+			opts: Options{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology := newRetryTopology("videos", tt.opts.withRetryDefaults())
+			if topology.deadLetter != "videos.dlq" {
+				t.Errorf("dead letter = %s", topology.deadLetter)
+			}
+			if strings.Join(topology.retryQueues, " ") != strings.Join(tt.queues, " ") {
+				t.Errorf("retry queues = %v, want %v", topology.retryQueues, tt.queues)
+			}
+
+			// Cada nome corresponde a um único TTL
+			ttls := make(map[string]time.Duration)
+			for i, name := range topology.retryQueues {
+				if ttl, ok := ttls[name]; ok && ttl != topology.delays[i] {
+					t.Errorf("queue %s declared with TTLs %v and %v", name, ttl, topology.delays[i])
+				}
+				ttls[name] = topology.delays[i]
+			}
+		})
+	}
+}
+
+func TestRetryTopologyNext(t *testing.T) {
+	topology := newRetryTopology("videos", Options{MaxRetries: 2, RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute})
+	logger := slog.New(slog.NewTextHandler(discard{}, nil))
+	transient := fmt.Errorf("connection reset")
+
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		retry   int
+		ok      bool
+	}{
+		{"first failure", 1, transient, 0, true},
+		{"second failure", 2, transient, 1, true},
+		{"retries exhausted", 3, transient, 0, false},
+		{"permanent error", 1, Permanent(transient), 0, false},
+		{"wrapped permanent error", 1, fmt.Errorf("download: %w", Permanent(transient)), 0, false},
+	}
+	for _, tt := range tests {
+		retry, ok := topology.next(logger, tt.attempt, tt.err)
+		if retry != tt.retry || ok != tt.ok {
+			t.Errorf("%s: next = %d, %v; want %d, %v", tt.name, retry, ok, tt.retry, tt.ok)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("timeout"), ErrorClassRetryable},
+		{Permanent(errors.New("404")), ErrorClassPermanent},
+		{fmt.Errorf("job: %w", ErrCanceled), ErrorClassCanceled},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+	if Permanent(nil) != nil {
+		t.Errorf("Permanent(nil) should be nil")
+	}
+}
+
+// discard descarta os logs dos testes
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,211 @@
+package queue
+
+// Importações necessárias para a validação das mensagens
+import (
+	"errors"       // Para o erro comum de mensagem inválida
+	"fmt"          // Para formatação de strings
+	"net/http"     // Para normalizar os nomes de headers
+	"net/url"      // Para validar a URL da fonte
+	"regexp"       // Para validar IDs e perfis
+	"strings"      // Para manipulação de strings
+	"unicode"      // Para rejeitar caracteres de controle
+	"unicode/utf8" // Para rejeitar textos que não são UTF-8
+)
+
+// Limites dos campos de VideoMessage
+const (
+	MaxIDLength       = 128  // O ID vira prefixo das chaves dos objetos
+	MaxURLLength      = 8192 // Tamanho máximo da URL da fonte
+	MaxFilenameLength = 255  // Limite de nome de arquivo da maioria dos sistemas de arquivos
+	MaxAuthHeaders    = 32   // Headers de autenticação por mensagem
+	MaxAuthValue      = 8192 // Tamanho máximo de cada valor de autenticação
+)
+
+// ErrInvalidMessage indica uma mensagem com campos inválidos
+// A mensagem nunca vai funcionar: vai direto para a dead-letter queue
+var ErrInvalidMessage = errors.New("invalid message")
+
+var (
+	// ValidID restringe o ID, que vira nome de diretório temporário e prefixo das chaves
+	ValidID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+	// validProfile segue as regras de nome de perfil do processador
+	validProfile = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	// validHeaderName aceita os caracteres de um token HTTP (RFC 9110)
+	validHeaderName = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+)
+
+// reservedHeaders são controlados pelo downloader e não podem vir na mensagem
+var reservedHeaders = map[string]bool{
+	"Host":              true,
+	"Range":             true,
+	"If-Range":          true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Te":                true,
+	"Upgrade":           true,
+}
+
+// Validate confere todos os campos da mensagem antes de qualquer trabalho
+// O ID e o nome do arquivo viram caminhos locais e chaves de objetos, então só
+// são aceitos valores que não saem do diretório ou do prefixo do job
+// O erro retornado embrulha ErrInvalidMessage
+func (m VideoMessage) Validate() error {
+	if !ValidID.MatchString(m.ID) {
+		return invalidf("id must be 1-%d letters, digits, '-' or '_'", MaxIDLength)
+	}
+
+	if m.URL == "" {
+		return invalidf("url is required")
+	}
+	if len(m.URL) > MaxURLLength {
+		return invalidf("url is longer than %d bytes", MaxURLLength)
+	}
+	if hasControlOrSpace(m.URL) {
+		return invalidf("url must not contain spaces or control characters")
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return invalidf("url is not a valid URL")
+	}
+	if u.Scheme == "" || u.Opaque != "" {
+		return invalidf("url must be an absolute URL")
+	}
+
+	if err := ValidateFilename(m.Filename); err != nil {
+		return err
+	}
+
+	if m.Profile != "" && !validProfile.MatchString(m.Profile) {
+		return invalidf("profile must be 1-64 letters, digits, '-' or '_'")
+	}
+
+	if m.Size < 0 {
+		return invalidf("size must not be negative")
+	}
+	if m.Checksum != "" {
+		if _, _, err := ParseChecksum(m.Checksum); err != nil {
+			return err
+		}
+	}
+
+	if m.Auth != nil {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return invalidf("auth is only supported for http and https URLs")
+		}
+		if err := m.Auth.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Algoritmos aceitos em VideoMessage.Checksum e o tamanho do hash em hexadecimal
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumMD5    = "md5"
+)
+
+// checksumLengths é o número de dígitos hexadecimais de cada algoritmo
+var checksumLengths = map[string]int{ChecksumSHA256: 64, ChecksumMD5: 32}
+
+// ParseChecksum separa "<algoritmo>:<hex>" no algoritmo e no hash em minúsculas
+func ParseChecksum(checksum string) (algorithm, sum string, err error) {
+	algorithm, sum, found := strings.Cut(checksum, ":")
+	algorithm, sum = strings.ToLower(algorithm), strings.ToLower(sum)
+	length, ok := checksumLengths[algorithm]
+	if !found || !ok {
+		return "", "", invalidf("checksum must be \"sha256:<hex>\" or \"md5:<hex>\"")
+	}
+	if len(sum) != length || strings.Trim(sum, "0123456789abcdef") != "" {
+		return "", "", invalidf("checksum must have %d hexadecimal digits for %s", length, algorithm)
+	}
+	return algorithm, sum, nil
+}
+
+// validate confere os headers e as credenciais basic de uma fonte
+// Os valores nunca aparecem nos erros, que podem ir para logs e dead-letter queue
+func (a *SourceAuth) validate() error {
+	if len(a.Headers) > MaxAuthHeaders {
+		return invalidf("auth has more than %d headers", MaxAuthHeaders)
+	}
+	for name, value := range a.Headers {
+		if !validHeaderName.MatchString(name) {
+			return invalidf("auth header name %q is invalid", name)
+		}
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return invalidf("auth header %q is not allowed", name)
+		}
+		if len(value) > MaxAuthValue || !validHeaderValue(value) {
+			return invalidf("auth header %q has an invalid value", name)
+		}
+	}
+
+	if a.Password != "" && a.Username == "" {
+		return invalidf("auth password requires a username")
+	}
+	if strings.Contains(a.Username, ":") {
+		return invalidf("auth username must not contain ':'")
+	}
+	if len(a.Username) > MaxAuthValue || len(a.Password) > MaxAuthValue ||
+		!validHeaderValue(a.Username) || !validHeaderValue(a.Password) {
+		return invalidf("auth username or password is invalid")
+	}
+	if a.Username != "" && hasHeader(a.Headers, "Authorization") {
+		return invalidf("auth must not have both basic credentials and an Authorization header")
+	}
+	return nil
+}
+
+// validHeaderValue rejeita caracteres de controle (exceto tab), que permitiriam
+// injetar headers
+func validHeaderValue(v string) bool {
+	return utf8.ValidString(v) && strings.IndexFunc(v, func(r rune) bool {
+		return r != '\t' && unicode.IsControl(r)
+	}) < 0
+}
+
+// hasHeader procura um header sem diferenciar maiúsculas de minúsculas
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFilename aceita apenas um nome de arquivo simples: sem separadores de
+// diretório, sem "." ou "..", sem caracteres de controle e em UTF-8
+func ValidateFilename(name string) error {
+	switch {
+	case name == "":
+		return invalidf("filename is required")
+	case len(name) > MaxFilenameLength:
+		return invalidf("filename is longer than %d bytes", MaxFilenameLength)
+	case name == "." || name == "..":
+		return invalidf("filename must be a plain file name")
+	case strings.ContainsAny(name, `/\:`):
+		return invalidf("filename must not contain path separators")
+	case !utf8.ValidString(name) || strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return invalidf("filename must be UTF-8 without control characters")
+	case strings.TrimSpace(name) != name:
+		return invalidf("filename must not start or end with spaces")
+	}
+	return nil
+}
+
+// invalidf cria um erro de validação que embrulha ErrInvalidMessage
+func invalidf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMessage, fmt.Sprintf(format, args...))
+}
+
+// hasControlOrSpace indica se s tem espaços, caracteres de controle ou não é UTF-8
+func hasControlOrSpace(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+	return strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsControl(r) || unicode.IsSpace(r)
+	}) >= 0
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validateCase altera uma mensagem válida e diz se o resultado continua válido
+type validateCase struct {
+	name   string
+	modify func(m *VideoMessage)
+	ok     bool
+}
+
+func runValidateCases(t *testing.T, tests []validateCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := VideoMessage{ID: "video-01", URL: "https://example.com/a.mp4", Filename: "a.mp4"}
+			tt.modify(&msg)
+			err := msg.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("expected a valid message, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("expected ErrInvalidMessage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVideoMessageValidate(t *testing.T) {
+	runValidateCases(t, []validateCase{
+		{"valid", func(m *VideoMessage) {}, true},
+		{"with profile", func(m *VideoMessage) { m.Profile = "mobile_low" }, true},
+		{"unicode filename", func(m *VideoMessage) { m.Filename = "aula 01 - introdução.mp4" }, true},
+
+		{"empty id", func(m *VideoMessage) { m.ID = "" }, false},
+		{"id with path", func(m *VideoMessage) { m.ID = "../etc" }, false},
+		{"id with dot", func(m *VideoMessage) { m.ID = "a.b" }, false},
+		{"id too long", func(m *VideoMessage) { m.ID = strings.Repeat("a", MaxIDLength+1) }, false},
+		{"empty url", func(m *VideoMessage) { m.URL = "" }, false},
+		{"relative url", func(m *VideoMessage) { m.URL = "/videos/a.mp4" }, false},
+		{"url with spaces", func(m *VideoMessage) { m.URL = "https://example.com/a b.mp4" }, false},
+		{"url with newline", func(m *VideoMessage) { m.URL = "https://example.com/a.mp4\nX: y" }, false},
+		{"url too long", func(m *VideoMessage) { m.URL = "https://example.com/" + strings.Repeat("a", MaxURLLength) }, false},
+		{"opaque url", func(m *VideoMessage) { m.URL = "mailto:someone@example.com" }, false},
+		{"empty filename", func(m *VideoMessage) { m.Filename = "" }, false},
+		{"dot dot filename", func(m *VideoMessage) { m.Filename = ".." }, false},
+		{"filename with slash", func(m *VideoMessage) { m.Filename = "../a.mp4" }, false},
+		{"filename with backslash", func(m *VideoMessage) { m.Filename = `..\a.mp4` }, false},
+		{"filename with colon", func(m *VideoMessage) { m.Filename = "C:a.mp4" }, false},
+		{"filename with control", func(m *VideoMessage) { m.Filename = "a\x00.mp4" }, false},
+		{"filename with spaces around", func(m *VideoMessage) { m.Filename = " a.mp4" }, false},
+		{"invalid utf-8 filename", func(m *VideoMessage) { m.Filename = "a\xff.mp4" }, false},
+		{"invalid profile", func(m *VideoMessage) { m.Profile = "../x" }, false},
+	})
+}
+
+func TestVideoMessageValidateSource(t *testing.T) {
+	runValidateCases(t, []validateCase{
+		{"s3 url", func(m *VideoMessage) { m.URL = "s3://videos/uploads/a.mp4" }, true},
+		{"basic auth", func(m *VideoMessage) { m.Auth = &SourceAuth{Username: "user", Password: "p@ss"} }, true},
+		{"header auth", func(m *VideoMessage) { m.Auth = &SourceAuth{Headers: map[string]string{"X-Api-Key": "k"}} }, true},
+
+		{"auth on s3", func(m *VideoMessage) { m.URL = "s3://videos/a.mp4"; m.Auth = &SourceAuth{Username: "u"} }, false},
+		{"reserved auth header", func(m *VideoMessage) { m.Auth = &SourceAuth{Headers: map[string]string{"host": "internal"}} }, false},
+		{"invalid auth header name", func(m *VideoMessage) { m.Auth = &SourceAuth{Headers: map[string]string{"X Key": "k"}} }, false},
+		{"header injection", func(m *VideoMessage) { m.Auth = &SourceAuth{Headers: map[string]string{"X-Key": "k\r\nHost: x"}} }, false},
+		{"password without username", func(m *VideoMessage) { m.Auth = &SourceAuth{Password: "p"} }, false},
+		{"username with colon", func(m *VideoMessage) { m.Auth = &SourceAuth{Username: "a:b"} }, false},
+		{"basic and authorization header", func(m *VideoMessage) {
+			m.Auth = &SourceAuth{Username: "u", Headers: map[string]string{"authorization": "Bearer t"}}
+		}, false},
+	})
+}
+
+// Os valores das credenciais nunca aparecem nos erros, que vão para logs e para a DLQ
+func TestValidateDoesNotLeakCredentials(t *testing.T) {
+	msg := VideoMessage{ID: "a", URL: "https://example.com/a.mp4", Filename: "a.mp4",
+		Auth: &SourceAuth{Headers: map[string]string{"X-Key": "super-secret\n"}}}
+	err := msg.Validate()
+	if err == nil || strings.Contains(err.Error(), "super-secret") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sha := strings.Repeat("ab", 32)
+	md := strings.Repeat("0f", 16)
+
+	tests := []struct {
+		in        string
+		algorithm string
+		sum       string
+		ok        bool
+	}{
+		{"sha256:" + sha, ChecksumSHA256, sha, true},
+		{"SHA256:" + strings.ToUpper(sha), ChecksumSHA256, sha, true},
+		{"md5:" + md, ChecksumMD5, md, true},
+		{"MD5:" + strings.ToUpper(md), ChecksumMD5, md, true},
+		{"sha256:" + md, "", "", false},             // Tamanho de MD5 com algoritmo SHA-256
+		{"md5:" + sha, "", "", false},               // E o contrário
+		{"sha256:" + sha[:63] + "g", "", "", false}, // Dígito não hexadecimal
+		{"sha1:" + strings.Repeat("a", 40), "", "", false},
+		{sha, "", "", false}, // Sem algoritmo
+		{"sha256:", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		algorithm, sum, err := ParseChecksum(tt.in)
+		if tt.ok {
+			if err != nil || algorithm != tt.algorithm || sum != tt.sum {
+				t.Errorf("ParseChecksum(%q) = %q, %q, %v; want %q, %q", tt.in, algorithm, sum, err, tt.algorithm, tt.sum)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("ParseChecksum(%q): expected ErrInvalidMessage, got %v", tt.in, err)
+		}
+	}
+}
+
+func TestVideoMessageValidateChecksum(t *testing.T) {
+	runValidateCases(t, []validateCase{
+		{"checksum and size", func(m *VideoMessage) { m.Checksum = "md5:" + strings.Repeat("a", 32); m.Size = 1024 }, true},
+		{"negative size", func(m *VideoMessage) { m.Size = -1 }, false},
+		{"invalid checksum", func(m *VideoMessage) { m.Checksum = "sha256:xyz" }, false},
+	})
+}
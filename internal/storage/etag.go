@@ -0,0 +1,59 @@
+package storage
+
+// Importações necessárias para calcular ETags
+import (
+	"crypto/md5"   // Hash usado pelo S3 nos ETags
+	"encoding/hex" // Para codificar o ETag
+	"fmt"          // Para formatação de strings
+	"io"           // Para ler o arquivo em partes
+	"os"           // Para abrir o arquivo
+)
+
+// MultipartPartSize é o tamanho das partes dos uploads multipart
+// Arquivos menores são enviados num único PUT
+const MultipartPartSize = 16 << 20
+
+// FileETag calcula o ETag que o arquivo recebe ao ser enviado por UploadFile,
+// no formato do S3: o MD5 do conteúdo ou, para arquivos com mais de uma parte,
+// o MD5 dos MD5 das partes seguido de "-<número de partes>"
+// Permite saber se um objeto já armazenado é igual ao arquivo local sem baixá-lo
+func FileETag(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	if info.Size() < MultipartPartSize {
+		h := md5.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	// Multipart: concatena o MD5 de cada parte e calcula o MD5 do resultado
+	var sums []byte
+	parts := 0
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, f, MultipartPartSize)
+		if n > 0 {
+			sums = h.Sum(sums)
+			parts++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+	}
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFileETag(t *testing.T) {
+	md5Hex := func(b []byte) string {
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:])
+	}
+	// ETag multipart do S3: MD5 da concatenação dos MD5 das partes, mais o número de partes
+	multipart := func(b []byte) string {
+		var sums []byte
+		parts := 0
+		for len(b) > 0 {
+			n := min(len(b), MultipartPartSize)
+			sum := md5.Sum(b[:n])
+			sums = append(sums, sum[:]...)
+			b = b[n:]
+			parts++
+		}
+		return md5Hex(sums) + "-" + strconv.Itoa(parts)
+	}
+
+	small := []byte("#EXTM3U\n#EXT-X-VERSION:3\n")
+	exact := bytes.Repeat([]byte{0xab}, MultipartPartSize)
+	large := bytes.Repeat([]byte("segment "), MultipartPartSize/8+1000)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, md5Hex(nil)},
+		{"small", small, md5Hex(small)},
+		// A partir do tamanho da parte o upload já é multipart
+		{"one full part", exact, multipart(exact)},
+		{"two parts", large, multipart(large)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "object")
+			if err := os.WriteFile(p, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := FileETag(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FileETag() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FileETag(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
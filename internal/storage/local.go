@@ -0,0 +1,268 @@
+package storage
+
+// Importações necessárias para o armazenamento em diretório local
+import (
+	"context"                    // Para controle de contexto
+	"errors"                     // Para identificar arquivos inexistentes
+	"fmt"                        // Para formatação de strings
+	"io"                         // Para copiar os arquivos
+	"io/fs"                      // Para percorrer o diretório
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para o logger do job
+	"net/url"                    // Para montar as URLs de leitura
+	"os"                         // Para operações de arquivo
+	"path/filepath"              // Para manipulação de caminhos de arquivos
+	"sort"                       // Para ordenar as listagens
+	"strings"                    // Para manipulação de strings
+	"sync"                       // Para o cache de ETags
+	"time"                       // Para a validade das URLs
+)
+
+// maxCachedETags limita o cache de ETags; ao passar do limite, ele é esvaziado
+const maxCachedETags = 10000
+
+// LocalBackend guarda os objetos como arquivos num diretório local
+// A chave do objeto é o caminho relativo ao diretório raiz
+type LocalBackend struct {
+	root    string // Diretório raiz dos objetos
+	baseURL string // URL pública que serve o diretório raiz ("" = URLs file://)
+
+	etagMu sync.Mutex            // Protege etags
+	etags  map[string]cachedETag // ETags já calculados, pelo caminho do arquivo
+}
+
+// cachedETag é o ETag de um arquivo, válido enquanto o tamanho e a data de
+// modificação não mudarem (cada upload grava um arquivo novo)
+type cachedETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+// NewLocalBackend cria o backend local e garante que o diretório existe
+// baseURL, se informado, é usado por PresignGet (ex: "http://localhost:8000/videos")
+func NewLocalBackend(root, baseURL string) (*LocalBackend, error) {
+	if root == "" {
+		return nil, errors.New("local storage directory is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve local storage directory: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local storage directory: %w", err)
+	}
+
+	slog.Info("Local storage initialized successfully", "dir", abs)
+	return &LocalBackend{
+		root:    abs,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		etags:   make(map[string]cachedETag),
+	}, nil
+}
+
+// pathFor converte uma chave no caminho do arquivo, sem permitir sair da raiz
+func (lb *LocalBackend) pathFor(objectKey string) (string, error) {
+	if err := validateKey(objectKey); err != nil {
+		return "", err
+	}
+	return filepath.Join(lb.root, filepath.FromSlash(objectKey)), nil
+}
+
+// UploadFile copia o arquivo para dentro do diretório raiz
+// A cópia é gravada num arquivo temporário e renomeada, para que leitores
+// nunca vejam um objeto pela metade
+func (lb *LocalBackend) UploadFile(ctx context.Context, filePath, objectKey, contentType string) error {
+	dst, err := lb.pathFor(objectKey)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // Sem efeito depois do rename
+
+	n, err := io.Copy(tmp, readerWithContext{ctx: ctx, r: src})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	logging.FromContext(ctx).Debug("Successfully uploaded", "key", objectKey, "bytes", n)
+	return nil
+}
+
+// Stat retorna as informações do objeto; o ETag é calculado como o do S3 (FileETag)
+// na primeira consulta e reaproveitado enquanto o arquivo não mudar
+func (lb *LocalBackend) Stat(ctx context.Context, objectKey string) (ObjectInfo, error) {
+	p, err := lb.pathFor(objectKey)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	etag, err := lb.etag(p, info)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return ObjectInfo{
+		Key:          objectKey,
+		Size:         info.Size(),
+		ETag:         etag,
+		ContentType:  contentTypeFor(objectKey),
+		LastModified: info.ModTime().UTC(),
+	}, nil
+}
+
+// etag retorna o ETag do arquivo, calculando-o só se ele mudou desde a última vez
+func (lb *LocalBackend) etag(p string, info fs.FileInfo) (string, error) {
+	lb.etagMu.Lock()
+	cached, ok := lb.etags[p]
+	lb.etagMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.etag, nil
+	}
+
+	etag, err := FileETag(p)
+	if err != nil {
+		return "", err
+	}
+
+	lb.etagMu.Lock()
+	defer lb.etagMu.Unlock()
+	if len(lb.etags) >= maxCachedETags {
+		lb.etags = make(map[string]cachedETag)
+	}
+	lb.etags[p] = cachedETag{size: info.Size(), modTime: info.ModTime(), etag: etag}
+	return etag, nil
+}
+
+// List retorna os objetos cuja chave começa com prefix
+// Só o diretório do prefixo é percorrido ("a/b/c" percorre "a/b")
+// O ETag não é calculado na listagem; use Stat quando precisar dele
+func (lb *LocalBackend) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	start := lb.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		dir := filepath.Join(lb.root, filepath.FromSlash(prefix[:i]))
+		if rel, err := filepath.Rel(lb.root, dir); err != nil || !filepath.IsLocal(rel) {
+			return nil, nil // Prefixo fora da raiz: nenhuma chave começa com ele
+		}
+		start = dir
+	}
+
+	var out []ObjectInfo
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == start {
+			return fs.SkipAll // Nenhum objeto com o prefixo
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(lb.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  contentTypeFor(key),
+			LastModified: info.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	sort.Slice(out, func(a, b int) bool { return out[a].Key < out[b].Key })
+	return out, nil
+}
+
+// Delete remove o arquivo do objeto
+func (lb *LocalBackend) Delete(ctx context.Context, objectKey string) error {
+	p, err := lb.pathFor(objectKey)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	lb.etagMu.Lock()
+	delete(lb.etags, p)
+	lb.etagMu.Unlock()
+	return nil
+}
+
+// PresignGet retorna a URL do objeto
+// Sem assinatura: com baseURL, a URL pública do arquivo; sem ela, uma URL file://
+func (lb *LocalBackend) PresignGet(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	p, err := lb.pathFor(objectKey)
+	if err != nil {
+		return "", err
+	}
+	if lb.baseURL != "" {
+		return lb.baseURL + "/" + (&url.URL{Path: objectKey}).EscapedPath(), nil
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String(), nil
+}
+
+// Health verifica se o diretório raiz existe e aceita escrita
+func (lb *LocalBackend) Health(ctx context.Context) error {
+	f, err := os.CreateTemp(lb.root, ".health-*")
+	if err != nil {
+		return fmt.Errorf("local storage directory is not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// readerWithContext interrompe a leitura quando o contexto é cancelado
+type readerWithContext struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r readerWithContext) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := map[string]bool{
+		"video-01/master.m3u8":     true,
+		"video-01/720p/segment.ts": true,
+		"aula 01/índice.m3u8":      true,
+		"":                         false,
+		"/etc/passwd":              false,
+		"../escape":                false,
+		"video-01/../../escape":    false,
+		"video-01/./master.m3u8":   false,
+		"video-01//master.m3u8":    false,
+		"video-01/":                false,
+		`video-01\..\master.m3u8`:  false,
+		".":                        false,
+	}
+	for key, ok := range tests {
+		if err := validateKey(key); (err == nil) != ok {
+			t.Errorf("validateKey(%q) = %v, want ok=%v", key, err, ok)
+		}
+	}
+}
+
+func TestLocalBackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	lb, err := NewLocalBackend(root, "http://localhost:8000/videos/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte("#EXTM3U\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lb.UploadFile(ctx, src, "video-01/master.m3u8", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := lb.UploadFile(ctx, src, "../escape", ""); err == nil {
+		t.Errorf("expected an error for a key outside the root")
+	}
+
+	info, err := lb.Stat(ctx, "video-01/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != "video-01/master.m3u8" || info.Size != 8 || info.ContentType != "application/vnd.apple.mpegurl" {
+		t.Errorf("Stat() = %+v", info)
+	}
+	// Diretórios não são objetos
+	if _, err := lb.Stat(ctx, "video-01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a directory, got %v", err)
+	}
+
+	// Nenhum arquivo temporário fica para trás
+	entries, err := os.ReadDir(filepath.Join(root, "video-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files in the object directory, want 1", len(entries))
+	}
+
+	url, err := lb.PresignGet(ctx, "video-01/aula 1.m3u8", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "http://localhost:8000/videos/video-01/aula%201.m3u8" {
+		t.Errorf("PresignGet() = %s", url)
+	}
+
+	if err := lb.Delete(ctx, "video-01/master.m3u8"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lb.Stat(ctx, "video-01/master.m3u8"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	// Remover um objeto que não existe não é erro
+	if err := lb.Delete(ctx, "video-01/master.m3u8"); err != nil {
+		t.Errorf("Delete() of a missing object: %v", err)
+	}
+}
+
+func TestLocalBackendList(t *testing.T) {
+	ctx := context.Background()
+	lb, err := NewLocalBackend(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a/master.m3u8", "a/720p/seg0.ts", "ab/master.m3u8", "b/master.m3u8", "root.txt"} {
+		if err := lb.UploadFile(ctx, src, key, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"a/720p/seg0.ts", "a/master.m3u8", "ab/master.m3u8", "b/master.m3u8", "root.txt"}},
+		{"a/", []string{"a/720p/seg0.ts", "a/master.m3u8"}},
+		{"a", []string{"a/720p/seg0.ts", "a/master.m3u8", "ab/master.m3u8"}},
+		{"a/720p/seg", []string{"a/720p/seg0.ts"}},
+		{"a/master", []string{"a/master.m3u8"}},
+		{"missing/", nil},
+		{"../", nil}, // Fora da raiz
+	}
+
+	for _, tt := range tests {
+		objects, err := lb.List(ctx, tt.prefix)
+		if err != nil {
+			t.Errorf("List(%q): %v", tt.prefix, err)
+			continue
+		}
+		var keys []string
+		for _, o := range objects {
+			keys = append(keys, o.Key)
+		}
+		if strings.Join(keys, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("List(%q) = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+}
+
+// O ETag em cache é recalculado quando o arquivo muda e descartado quando ele é removido
+func TestLocalBackendStatETag(t *testing.T) {
+	ctx := context.Background()
+	lb, err := NewLocalBackend(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "src")
+	upload := func(data string) string {
+		if err := os.WriteFile(src, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := lb.UploadFile(ctx, src, "v/master.m3u8", ""); err != nil {
+			t.Fatal(err)
+		}
+		etag, err := FileETag(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return etag
+	}
+
+	first := upload("first")
+	for i := 0; i < 2; i++ {
+		info, err := lb.Stat(ctx, "v/master.m3u8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ETag != first || info.Size != 5 {
+			t.Errorf("Stat() = %+v, want etag %s", info, first)
+		}
+	}
+
+	// Mesmo tamanho, data de modificação diferente
+	second := upload("other")
+	p, _ := lb.pathFor("v/master.m3u8")
+	if err := os.Chtimes(p, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := lb.Stat(ctx, "v/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ETag != second {
+		t.Errorf("Stat() returned a stale etag %s, want %s", info.ETag, second)
+	}
+
+	if err := lb.Delete(ctx, "v/master.m3u8"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lb.Stat(ctx, "v/master.m3u8"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if len(lb.etags) != 0 {
+		t.Errorf("deleted object still cached")
+	}
+}
@@ -1,13 +1,19 @@
 // Package storage contém implementações para armazenamento de arquivos
-// Este pacote usa MinIO (compatível com Amazon S3) para armazenar arquivos processados
+// Os arquivos processados vão para o MinIO (compatível com Amazon S3) ou para
+// um diretório local, ambos atrás da interface Backend
 package storage
 
 // Importações necessárias para armazenamento MinIO
 import (
-	"context" // Para controle de contexto
-	"fmt"     // Para formatação de strings
-	"log"     // Para logging
-	"os"      // Para operações de arquivo
+	"context"                    // Para controle de contexto
+	"fmt"                        // Para formatação de strings
+	"io"                         // Para a leitura de objetos
+	"log/slog"                   // Para logging estruturado
+	"ms-videos/internal/logging" // Para o logger do job
+	"net/http"                   // Para identificar objetos inexistentes
+	"os"                         // Para operações de arquivo
+	"strings"                    // Para normalizar o ETag
+	"time"                       // Para a validade das URLs assinadas
 
 	"github.com/minio/minio-go/v7"                 // Cliente MinIO
 	"github.com/minio/minio-go/v7/pkg/credentials" // Credenciais MinIO
@@ -44,7 +50,7 @@ func NewMinIOClient(endpoint, accessKey, secretKey, bucketName string) (*MinIOCl
 		return nil, fmt.Errorf("failed to ensure bucket exists: %w", err)
 	}
 
-	log.Printf("MinIO client initialized successfully for bucket: %s", bucketName)
+	slog.Info("MinIO client initialized successfully", "bucket", bucketName)
 	return client, nil
 }
 
@@ -65,21 +71,33 @@ func (mc *MinIOClient) ensureBucketExists() error {
 		if err != nil {
 			return fmt.Errorf("failed to create bucket: %w", err)
 		}
-		log.Printf("Created bucket: %s", mc.bucketName)
+		slog.Info("Created bucket", "bucket", mc.bucketName)
 	} else {
-		log.Printf("Bucket already exists: %s", mc.bucketName)
+		slog.Debug("Bucket already exists", "bucket", mc.bucketName)
 	}
 
 	return nil
 }
 
+// Health verifica se o MinIO responde e se o bucket existe e está acessível
+// com as credenciais configuradas (a mesma consulta de ensureBucketExists)
+func (mc *MinIOClient) Health(ctx context.Context) error {
+	exists, err := mc.client.BucketExists(ctx, mc.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to check if bucket exists: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", mc.bucketName)
+	}
+	return nil
+}
+
 // UploadFile faz o upload de um arquivo local para o armazenamento MinIO
 // filePath: caminho do arquivo local
 // objectKey: nome/chave do objeto no armazenamento
 // contentType: tipo MIME do arquivo (ex: "video/mp4", "application/vnd.apple.mpegurl")
-func (mc *MinIOClient) UploadFile(filePath, objectKey, contentType string) error {
-	ctx := context.Background() // Contexto para a operação
-
+// Cancelar ctx interrompe o upload em andamento
+func (mc *MinIOClient) UploadFile(ctx context.Context, filePath, objectKey, contentType string) error {
 	// Abre o arquivo local para leitura
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -96,12 +114,121 @@ func (mc *MinIOClient) UploadFile(filePath, objectKey, contentType string) error
 
 	// Faz o upload do arquivo para MinIO
 	_, err = mc.client.PutObject(ctx, mc.bucketName, objectKey, file, fileInfo.Size(), minio.PutObjectOptions{
-		ContentType: contentType, // Define o tipo de conteúdo para o arquivo
+		ContentType: contentType,       // Define o tipo de conteúdo para o arquivo
+		PartSize:    MultipartPartSize, // Partes fixas, para que FileETag preveja o ETag
 	})
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 
-	log.Printf("Successfully uploaded: %s", objectKey)
+	logging.FromContext(ctx).Debug("Successfully uploaded", "key", objectKey, "bytes", fileInfo.Size())
 	return nil
 }
+
+// Stat retorna as informações do objeto, ou ErrNotFound
+func (mc *MinIOClient) Stat(ctx context.Context, objectKey string) (ObjectInfo, error) {
+	info, err := mc.client.StatObject(ctx, mc.bucketName, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return objectInfo(info), nil
+}
+
+// List retorna os objetos cuja chave começa com prefix
+func (mc *MinIOClient) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var out []ObjectInfo
+	for info := range mc.client.ListObjects(ctx, mc.bucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true, // Inclui os objetos das subpastas (variantes)
+	}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", info.Err)
+		}
+		out = append(out, objectInfo(info))
+	}
+	return out, nil
+}
+
+// Delete remove o objeto do bucket
+func (mc *MinIOClient) Delete(ctx context.Context, objectKey string) error {
+	err := mc.client.RemoveObject(ctx, mc.bucketName, objectKey, minio.RemoveObjectOptions{})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// PresignGet gera uma URL assinada para leitura do objeto, válida por expiry
+func (mc *MinIOClient) PresignGet(ctx context.Context, objectKey string, expiry time.Duration) (string, error) {
+	u, err := mc.client.PresignedGetObject(ctx, mc.bucketName, objectKey, expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign object: %w", err)
+	}
+	return u.String(), nil
+}
+
+// StatObject retorna as informações de um objeto de qualquer bucket acessível
+func (mc *MinIOClient) StatObject(ctx context.Context, bucket, objectKey string) (ObjectInfo, error) {
+	info, err := mc.client.StatObject(ctx, bucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, objectError("failed to stat object", err)
+	}
+	return objectInfo(info), nil
+}
+
+// OpenObject abre um objeto de qualquer bucket acessível a partir de offset
+func (mc *MinIOClient) OpenObject(ctx context.Context, bucket, objectKey string, offset int64, etag string) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if offset > 0 {
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, fmt.Errorf("failed to open object: %w", err)
+		}
+	}
+	if etag != "" {
+		if err := opts.SetMatchETag(etag); err != nil {
+			return nil, fmt.Errorf("failed to open object: %w", err)
+		}
+	}
+
+	obj, err := mc.client.GetObject(ctx, bucket, objectKey, opts)
+	if err != nil {
+		return nil, objectError("failed to open object", err)
+	}
+	// GetObject é preguiçoso: Stat faz a requisição e revela os erros
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, objectError("failed to open object", err)
+	}
+	return obj, nil
+}
+
+// objectError converte os erros de leitura do MinIO nos erros comuns aos backends
+func objectError(msg string, err error) error {
+	if isNotFound(err) {
+		return fmt.Errorf("%s: %w", msg, ErrNotFound)
+	}
+	if resp := minio.ToErrorResponse(err); resp.StatusCode == http.StatusForbidden || resp.Code == "AccessDenied" {
+		return fmt.Errorf("%s: %w", msg, ErrAccessDenied)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// objectInfo converte as informações do MinIO para ObjectInfo
+func objectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         strings.Trim(info.ETag, `"`),
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
+
+// isNotFound indica se o erro do MinIO é de objeto inexistente
+func isNotFound(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey"
+}
@@ -0,0 +1,92 @@
+package storage
+
+// Importações necessárias para a interface de armazenamento
+import (
+	"context" // Para controle de contexto
+	"errors"  // Para os erros comuns aos backends
+	"fmt"     // Para formatação de strings
+	"io"      // Para a leitura de objetos
+	"mime"    // Para deduzir o tipo de conteúdo pela extensão
+	"path"    // Para validar chaves de objetos (sempre com "/")
+	"strings" // Para manipulação de strings
+	"time"    // Para datas e validade de URLs assinadas
+)
+
+// Backends de armazenamento disponíveis
+const (
+	BackendMinIO = "minio" // MinIO ou outro serviço compatível com S3
+	BackendLocal = "local" // Diretório local, para desenvolvimento, on-prem e testes
+)
+
+// Erros comuns aos backends
+var (
+	ErrNotFound     = errors.New("object not found")        // O objeto (ou o bucket) não existe
+	ErrAccessDenied = errors.New("object access is denied") // As credenciais não dão acesso ao objeto
+)
+
+// ObjectInfo descreve um objeto armazenado
+type ObjectInfo struct {
+	Key          string    // Chave do objeto (ex: "video-id/720p/playlist.m3u8")
+	Size         int64     // Tamanho em bytes
+	ETag         string    // Hash do conteúdo no formato do S3 (veja FileETag)
+	ContentType  string    // Tipo MIME
+	LastModified time.Time // Momento da última escrita
+}
+
+// Backend é o armazenamento onde os arquivos HLS são publicados
+// Implementado por MinIOClient e LocalBackend
+type Backend interface {
+	// UploadFile envia um arquivo local para objectKey, substituindo o objeto existente
+	UploadFile(ctx context.Context, filePath, objectKey, contentType string) error
+	// Stat retorna as informações do objeto, ou ErrNotFound
+	Stat(ctx context.Context, objectKey string) (ObjectInfo, error)
+	// List retorna os objetos cuja chave começa com prefix, em ordem de chave
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete remove o objeto; remover um objeto inexistente não é erro
+	Delete(ctx context.Context, objectKey string) error
+	// PresignGet retorna uma URL de leitura do objeto válida por expiry
+	PresignGet(ctx context.Context, objectKey string, expiry time.Duration) (string, error)
+	// Health verifica se o armazenamento está acessível
+	Health(ctx context.Context) error
+}
+
+// ObjectReader lê objetos de qualquer bucket acessível com as credenciais do
+// backend; é usado para baixar fontes s3:// sem URLs assinadas
+// Implementado por MinIOClient
+type ObjectReader interface {
+	// StatObject retorna as informações do objeto, ErrNotFound ou ErrAccessDenied
+	StatObject(ctx context.Context, bucket, objectKey string) (ObjectInfo, error)
+	// OpenObject abre o objeto a partir de offset
+	// Com etag, a leitura falha se o objeto mudou desde que o ETag foi obtido
+	OpenObject(ctx context.Context, bucket, objectKey string, offset int64, etag string) (io.ReadCloser, error)
+}
+
+// validateKey rejeita chaves vazias, absolutas ou que saiam do prefixo com ".."
+func validateKey(objectKey string) error {
+	if objectKey == "" || strings.HasPrefix(objectKey, "/") || strings.Contains(objectKey, "\\") {
+		return fmt.Errorf("invalid object key %q", objectKey)
+	}
+	if path.Clean(objectKey) != objectKey {
+		return fmt.Errorf("invalid object key %q", objectKey)
+	}
+	for _, part := range strings.Split(objectKey, "/") {
+		if part == ".." || part == "." {
+			return fmt.Errorf("invalid object key %q", objectKey)
+		}
+	}
+	return nil
+}
+
+// contentTypeFor deduz o tipo de conteúdo de uma chave pela extensão
+func contentTypeFor(objectKey string) string {
+	switch path.Ext(objectKey) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	}
+	if t := mime.TypeByExtension(path.Ext(objectKey)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
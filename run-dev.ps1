@@ -67,7 +67,7 @@ if ($services -contains "rabbitmq" -and $services -contains "minio") {
 
 Write-Host ""
 Write-Host "Building application..." -ForegroundColor Yellow
-go build -o ms-videos.exe cmd/ms-videos/main.go
+go build -o ms-videos.exe ./cmd/ms-videos
 
 if ($LASTEXITCODE -eq 0) {
     Write-Host "✓ Build successful" -ForegroundColor Green